@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+var errUnknownPalette = errors.New("unknown palette")
+
+// ANSI SGR sequences used by the colored text output.
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiItalic    = "\x1b[3m"
+	ansiUnderline = "\x1b[4m"
+)
+
+// terminalClients are User-Agent prefixes of command-line HTTP clients that
+// print response bodies to a terminal, so they get colors by default.
+var terminalClients = []string{"curl/", "HTTPie/", "xh/"}
+
+type rgb struct{ R, G, B uint8 }
+
+// ansiPalette colors the art. Gradient palettes blend their stops along
+// the diagonal of the art; rainbow cycles through hues like lolcat.
+type ansiPalette struct {
+	Name    string
+	rainbow bool
+	stops   []rgb
+	// prompt and quote color the shell prompt and the quote below the art.
+	prompt rgb
+	quote  rgb
+}
+
+// ansiPalettes lists the built-in palettes; the first one is the default.
+var ansiPalettes = []ansiPalette{
+	{Name: "rainbow", rainbow: true, prompt: rgb{0x25, 0xd3, 0x66}, quote: rgb{0xD6, 0xA2, 0xE8}},
+	{Name: "friendship", stops: []rgb{{0x58, 0xB1, 0x9F}, {0xD6, 0xA2, 0xE8}, {0xFD, 0x72, 0x72}}, prompt: rgb{0x58, 0xB1, 0x9F}, quote: rgb{0xD6, 0xA2, 0xE8}},
+	{Name: "sunset", stops: []rgb{{0xFF, 0x5F, 0x6D}, {0xFF, 0xC3, 0x71}}, prompt: rgb{0xFF, 0xC3, 0x71}, quote: rgb{0xFF, 0x9A, 0x8B}},
+	{Name: "ocean", stops: []rgb{{0x2E, 0x31, 0x92}, {0x1B, 0xFF, 0xFF}}, prompt: rgb{0x1B, 0xFF, 0xFF}, quote: rgb{0x7F, 0xDB, 0xFF}},
+}
+
+// ansiOptions selects how colored output is drawn.
+type ansiOptions struct {
+	Palette   ansiPalette
+	TrueColor bool
+}
+
+// colorFromRequest decides whether the text response is colored and how.
+// The no_color parameter always turns colors off, following the NO_COLOR
+// convention that its presence alone counts. Otherwise color=0/1 forces
+// them off or on, color=256 and color=truecolor pick the color depth, and
+// without the parameter terminal clients get 256 colors.
+func colorFromRequest(r *http.Request) (ansiOptions, bool, error) {
+	query := r.URL.Query()
+	palette, err := lookupPalette(query.Get("palette"))
+	if err != nil {
+		return ansiOptions{}, false, err
+	}
+	opts := ansiOptions{Palette: palette}
+	if !cfg.Features.Color || query.Has("no_color") {
+		return opts, false, nil
+	}
+
+	switch value := strings.ToLower(query.Get("color")); value {
+	case "":
+		return opts, isTerminalClient(r.UserAgent()), nil
+	case "256", "on":
+		return opts, true, nil
+	case "off":
+		return opts, false, nil
+	case "truecolor", "24bit":
+		opts.TrueColor = true
+		return opts, true, nil
+	default:
+		on, err := strconv.ParseBool(value)
+		if err != nil {
+			return ansiOptions{}, false, fmt.Errorf("color must be 0, 1, 256 or truecolor, got %q", value)
+		}
+		return opts, on, nil
+	}
+}
+
+func isTerminalClient(userAgent string) bool {
+	for _, prefix := range terminalClients {
+		if strings.HasPrefix(userAgent, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupPalette returns the named palette, or the default when name is empty.
+func lookupPalette(name string) (ansiPalette, error) {
+	if name == "" {
+		return ansiPalettes[0], nil
+	}
+	names := make([]string, len(ansiPalettes))
+	for i, p := range ansiPalettes {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+		names[i] = p.Name
+	}
+	return ansiPalette{}, fmt.Errorf("%w %q (available: %s)", errUnknownPalette, name, strings.Join(names, ", "))
+}
+
+// fg returns the SGR sequence setting the foreground to c.
+func (o ansiOptions) fg(c rgb) string {
+	if o.TrueColor {
+		return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("\x1b[38;5;%dm", xterm256(c))
+}
+
+// xterm256 maps c to the nearest color of the xterm 6×6×6 color cube.
+func xterm256(c rgb) int {
+	level := func(v uint8) int { return int(math.Round(float64(v) / 255 * 5)) }
+	return 16 + 36*level(c.R) + 6*level(c.G) + level(c.B)
+}
+
+// colorAt returns the art color at a position along the diagonal, where
+// t runs from 0 at the top left to 1 at the bottom right.
+func (p ansiPalette) colorAt(t float64) rgb {
+	if p.rainbow {
+		// lolcat's sine rainbow, spread over about one and a half cycles.
+		x := t * 3 * math.Pi
+		channel := func(phase float64) uint8 { return uint8(math.Sin(x+phase)*127 + 128) }
+		return rgb{channel(0), channel(2 * math.Pi / 3), channel(4 * math.Pi / 3)}
+	}
+	if len(p.stops) == 1 {
+		return p.stops[0]
+	}
+	t = min(max(t, 0), 1) * float64(len(p.stops)-1)
+	i := min(int(t), len(p.stops)-2)
+	f := t - float64(i)
+	a, b := p.stops[i], p.stops[i+1]
+	mix := func(x, y uint8) uint8 { return uint8(math.Round(float64(x) + (float64(y)-float64(x))*f)) }
+	return rgb{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B)}
+}
+
+// colorize paints every visible character of text with the palette. Text
+// is walked by grapheme cluster and positions are counted in terminal
+// columns, so wide characters and emoji sequences are colored as one
+// character and the gradient stays aligned across lines.
+func (o ansiOptions) colorize(text string) string {
+	lines := strings.Split(text, "\n")
+	cols := 1
+	for _, line := range lines {
+		cols = max(cols, displayWidth(line))
+	}
+	// Columns count half as much as lines, as terminal cells are about
+	// twice as tall as they are wide.
+	span := float64(cols)/2 + float64(len(lines))
+
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		col, last := 0, ""
+		state := -1
+		for line != "" {
+			var cluster string
+			var width int
+			cluster, line, width, state = uniseg.FirstGraphemeClusterInString(line, state)
+			if strings.TrimSpace(cluster) != "" {
+				// Neighbours often map to the same 256-color index.
+				if seq := o.fg(o.Palette.colorAt((float64(col)/2 + float64(i)) / span)); seq != last {
+					b.WriteString(seq)
+					last = seq
+				}
+			}
+			b.WriteString(cluster)
+			col += width
+		}
+		if last != "" {
+			b.WriteString(ansiReset)
+		}
+	}
+	return b.String()
+}
+
+// ansi lays the greeting out like terminal, with the art in the palette's
+// colors, the prompt in its prompt color and the quote in italics.
+func (g greeting) ansi(o ansiOptions) string {
+	prompt := o.fg(o.Palette.prompt)
+	var b strings.Builder
+	b.WriteString("\n " + prompt + "wishes@" + ansiReset + ansiBold + g.Name + ansiReset + prompt + ":~" + ansiReset + "💚" + prompt + "$" + ansiReset)
+	b.WriteString(o.colorize(g.Art))
+	b.WriteString("\n")
+	quote := o.fg(o.Palette.quote) + ansiItalic
+	for i, line := range strings.Split(g.Quote.Text, "\n") {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(" " + quote + line + ansiReset)
+	}
+	return b.String()
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var sgrPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func TestColorFromRequest(t *testing.T) {
+	tests := []struct {
+		query     string
+		userAgent string
+		colored   bool
+		trueColor bool
+	}{
+		{"", "", false, false},
+		{"", "Mozilla/5.0", false, false},
+		{"", "curl/8.5.0", true, false},
+		{"", "HTTPie/3.2.2", true, false},
+		{"", "xh/0.22.0", true, false},
+		{"color=1", "", true, false},
+		{"color=true", "Mozilla/5.0", true, false},
+		{"color=256", "", true, false},
+		{"color=truecolor", "", true, true},
+		{"color=24bit", "", true, true},
+		{"color=0", "curl/8.5.0", false, false},
+		{"color=off", "curl/8.5.0", false, false},
+		{"no_color", "curl/8.5.0", false, false},
+		{"no_color=&color=1", "curl/8.5.0", false, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/wish?"+tt.query, nil)
+		if tt.userAgent != "" {
+			r.Header.Set("User-Agent", tt.userAgent)
+		}
+		opts, colored, err := colorFromRequest(r)
+		if err != nil {
+			t.Errorf("%q with %q: %v", tt.query, tt.userAgent, err)
+			continue
+		}
+		if colored != tt.colored || opts.TrueColor != tt.trueColor {
+			t.Errorf("%q with %q: colored = %v, truecolor = %v; want %v, %v", tt.query, tt.userAgent, colored, opts.TrueColor, tt.colored, tt.trueColor)
+		}
+	}
+}
+
+func TestColorFromRequestErrors(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/wish?palette=neon", nil)
+	if _, _, err := colorFromRequest(r); !errors.Is(err, errUnknownPalette) || !strings.Contains(err.Error(), "friendship") {
+		t.Errorf("palette=neon: err = %v, want errUnknownPalette listing the palettes", err)
+	}
+	r = httptest.NewRequest(http.MethodGet, "/wish?color=lots", nil)
+	if _, _, err := colorFromRequest(r); err == nil {
+		t.Error("color=lots: want an error")
+	}
+}
+
+func TestColorFeatureDisabled(t *testing.T) {
+	setConfig(t, func(c *config) { c.Features.Color = false })
+	rec := doRequest(t, http.MethodGet, "/wish?name=Sam&color=1", nil)
+	if strings.Contains(rec.Body.String(), "\x1b[") {
+		t.Errorf("body contains escape sequences with the feature off:\n%q", rec.Body)
+	}
+}
+
+func TestTerminalClientGetsColors(t *testing.T) {
+	rec := doRequest(t, http.MethodGet, "/wish?name=Sam", http.Header{"User-Agent": {"curl/8.5.0"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "\x1b[38;5;") {
+		t.Errorf("curl did not get 256-color output:\n%q", body)
+	}
+	if got := rec.Header().Values("Vary"); !slices.Contains(got, "User-Agent") {
+		t.Errorf("Vary = %q, want User-Agent", got)
+	}
+
+	plain := doRequest(t, http.MethodGet, "/wish?name=Sam", nil).Body.String()
+	if stripped := sgrPattern.ReplaceAllString(body, ""); stripped != plain {
+		t.Errorf("colored output without escapes differs from plain output:\n%s\nwant:\n%s", stripped, plain)
+	}
+}
+
+func TestTrueColorPalettes(t *testing.T) {
+	for _, p := range ansiPalettes {
+		query := url.Values{"name": {"Sam"}, "color": {"truecolor"}, "palette": {p.Name}}
+		rec := doRequest(t, http.MethodGet, "/wish?"+query.Encode(), nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("palette %s: status = %d, want 200", p.Name, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), "\x1b[38;2;") {
+			t.Errorf("palette %s: no truecolor sequences in output", p.Name)
+		}
+		if strings.Contains(rec.Body.String(), "\x1b[38;5;") {
+			t.Errorf("palette %s: 256-color sequences in truecolor output", p.Name)
+		}
+	}
+	rec := doRequest(t, http.MethodGet, "/wish?name=Sam&color=1&palette=neon", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown palette: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestColorizeWideCharacters(t *testing.T) {
+	opts := ansiOptions{Palette: ansiPalettes[1], TrueColor: true}
+	// Two wide characters, a flag made of two code points and an ASCII letter.
+	got := opts.colorize("友達🇯🇵a")
+	if n := strings.Count(got, "\x1b[38;2;"); n != 4 {
+		t.Errorf("colorize set %d colors, want one per grapheme cluster (4):\n%q", n, got)
+	}
+	if stripped := sgrPattern.ReplaceAllString(got, ""); stripped != "友達🇯🇵a" {
+		t.Errorf("colorize changed the text: %q", stripped)
+	}
+}
+
+func TestCardAlignsWideNames(t *testing.T) {
+	for _, name := range []string{"さくら", "Zoë", "민준 🎉"} {
+		art := cardStyle(name, artOptions{})
+		var widths []int
+		for _, line := range strings.Split(strings.Trim(art, "\n"), "\n") {
+			if strings.TrimSpace(line) != "" {
+				widths = append(widths, displayWidth(line))
+			}
+		}
+		for _, w := range widths[1:] {
+			if w != widths[0] {
+				t.Errorf("%s: card lines have widths %v, want them equal:\n%s", name, widths, art)
+				break
+			}
+		}
+	}
+}
+
+func TestXterm256(t *testing.T) {
+	tests := []struct {
+		c    rgb
+		want int
+	}{
+		{rgb{0, 0, 0}, 16},
+		{rgb{255, 255, 255}, 231},
+		{rgb{255, 0, 0}, 196},
+		{rgb{0x58, 0xB1, 0x9F}, 109},
+	}
+	for _, tt := range tests {
+		if got := xterm256(tt.c); got != tt.want {
+			t.Errorf("xterm256(%v) = %d, want %d", tt.c, got, tt.want)
+		}
+	}
+}
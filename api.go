@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// apiWish is the JSON representation of a greeting.
+type apiWish struct {
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	Art      string `json:"art"`
+	Quote    string `json:"quote"`
+	QuoteID  string `json:"quote_id"`
+	ShareURL string `json:"share_url"`
+	ImageURL string `json:"image_url"`
+}
+
+// apiError is the JSON body returned for failed API requests.
+type apiError struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+type apiErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// artErrorCode maps an artFromRequest error to a machine-readable code.
+func artErrorCode(err error) string {
+	switch {
+	case errors.Is(err, errUnknownStyle):
+		return "unknown_style"
+	case errors.Is(err, errUnknownQuote):
+		return "unknown_quote"
+	}
+	return "unknown_font"
+}
+
+// writeJSON encodes v as the response body with the given status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	setJSONHeaders(w)
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+// writeAPIError writes a JSON error body with the given status.
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, apiError{Error: apiErrorDetail{Code: code, Message: message}})
+}
+
+// apiWishHandler handles requests for JSON responses for wishes.
+func apiWishHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeAPIError(w, http.StatusBadRequest, "name_required", "Name is required")
+		return
+	}
+
+	validName, err := validateName(name)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, nameErrorCode(err), err.Error())
+		return
+	}
+
+	style, opts, err := artFromRequest(r, validName)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, artErrorCode(err), err.Error())
+		return
+	}
+
+	g := newGreeting(validName, style, opts)
+	baseURL := publicBaseURL(r)
+
+	formatUsage.inc("json")
+	writeJSON(w, http.StatusOK, apiWish{
+		Name:     g.Name,
+		Slug:     g.Slug,
+		Art:      strings.Trim(g.Art, "\n\t"),
+		Quote:    g.Quote.Text,
+		QuoteID:  g.Quote.ID,
+		ShareURL: shareURL(baseURL, g.Slug),
+		ImageURL: imageURL(baseURL, g.Slug),
+	})
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// imageBackendLocal renders greeting cards with the built-in PNG renderer.
+const imageBackendLocal = "local"
+
+// config holds the server settings. Values are layered, lowest precedence
+// first: defaults, config file, WISH_* environment variables, flags.
+type config struct {
+	Listen             string          `yaml:"listen"`
+	AdminListen        string          `yaml:"admin_listen"`
+	PublicURL          string          `yaml:"public_url"`
+	PublicHosts        []string        `yaml:"public_hosts"`
+	ImageBackend       string          `yaml:"image_backend"`
+	QuotesFile         string          `yaml:"quotes_file"`
+	TemplatesDir       string          `yaml:"templates_dir"`
+	ReadTimeout        time.Duration   `yaml:"read_timeout"`
+	ReadHeaderTimeout  time.Duration   `yaml:"read_header_timeout"`
+	WriteTimeout       time.Duration   `yaml:"write_timeout"`
+	IdleTimeout        time.Duration   `yaml:"idle_timeout"`
+	ShutdownTimeout    time.Duration   `yaml:"shutdown_timeout"`
+	ShutdownDelay      time.Duration   `yaml:"shutdown_delay"`
+	LiveMaxDuration    time.Duration   `yaml:"live_max_duration"`
+	MaxHeaderBytes     int             `yaml:"max_header_bytes"`
+	LogFormat          string          `yaml:"log_format"`
+	TrustedProxies     []string        `yaml:"trusted_proxies"`
+	TransliterateSlugs bool            `yaml:"transliterate_slugs"`
+	RateLimits         rateLimits      `yaml:"rate_limits"`
+	SecurityHeaders    securityHeaders `yaml:"security_headers"`
+	Features           features        `yaml:"features"`
+}
+
+// rateLimits maps a route path such as /wish/text to its per-client limit.
+type rateLimits map[string]rateLimit
+
+// securityHeaders is the header policy sent with every response. Empty
+// values leave the header out. "{nonce}" in the Content-Security-Policy is
+// replaced with the nonce of the request's inline scripts and styles.
+type securityHeaders struct {
+	ContentSecurityPolicy string `yaml:"content_security_policy"`
+	CSPReportOnly         bool   `yaml:"csp_report_only"`
+	ReferrerPolicy        string `yaml:"referrer_policy"`
+	PermissionsPolicy     string `yaml:"permissions_policy"`
+	FrameOptions          string `yaml:"frame_options"`
+	// HSTS is only sent on requests that arrived over HTTPS, directly or
+	// through a trusted proxy.
+	HSTS string `yaml:"hsts"`
+}
+
+// features toggles optional endpoints.
+type features struct {
+	Image     bool `yaml:"image"`
+	SVG       bool `yaml:"svg"`
+	API       bool `yaml:"api"`
+	RateLimit bool `yaml:"rate_limit"`
+	Metrics   bool `yaml:"metrics"`
+	Color     bool `yaml:"color"`
+	Live      bool `yaml:"live"`
+}
+
+func defaultConfig() config {
+	return config{
+		Listen:            ":6054",
+		ImageBackend:      imageBackendLocal,
+		ReadTimeout:       10 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		ShutdownTimeout:   15 * time.Second,
+		LiveMaxDuration:   30 * time.Second,
+		MaxHeaderBytes:    16 << 10,
+		LogFormat:         "text",
+		RateLimits: rateLimits{
+			"/wish":           {Rate: 2, Burst: 20},
+			"/wish/web":       {Rate: 2, Burst: 20},
+			"/wish/text":      {Rate: 2, Burst: 20},
+			"/wish/svg":       {Rate: 2, Burst: 20},
+			"/wish/image.png": {Rate: 0.5, Burst: 5},
+			"/wish/live":      {Rate: 0.2, Burst: 3},
+			"/api/v1/wish":    {Rate: 5, Burst: 50},
+			"/api/v1/slug":    {Rate: 5, Burst: 50},
+			"/csp-report":     {Rate: 1, Burst: 10},
+		},
+		SecurityHeaders: securityHeaders{
+			ContentSecurityPolicy: defaultCSP,
+			ReferrerPolicy:        "strict-origin-when-cross-origin",
+			PermissionsPolicy:     "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+			FrameOptions:          "DENY",
+			HSTS:                  "max-age=31536000; includeSubDomains",
+		},
+		Features: features{Image: true, SVG: true, API: true, RateLimit: true, Metrics: true, Color: true, Live: true},
+	}
+}
+
+// cfg is the effective configuration used by the handlers.
+var cfg = defaultConfig()
+
+// setting describes one option that can be set from the environment or a
+// flag. The environment variable is WISH_ followed by the upper-cased flag
+// name with dashes turned into underscores.
+type setting struct {
+	flag   string
+	usage  string
+	set    func(c *config, value string) error
+	isBool bool
+}
+
+func (s setting) env() string {
+	return "WISH_" + strings.ToUpper(strings.ReplaceAll(s.flag, "-", "_"))
+}
+
+func stringSetting(field func(*config) *string) func(*config, string) error {
+	return func(c *config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func durationSetting(field func(*config) *time.Duration) func(*config, string) error {
+	return func(c *config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+func intSetting(field func(*config) *int) func(*config, string) error {
+	return func(c *config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func boolSetting(field func(*config) *bool) func(*config, string) error {
+	return func(c *config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func listSetting(field func(*config) *[]string) func(*config, string) error {
+	return func(c *config, value string) error {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*field(c) = list
+		return nil
+	}
+}
+
+// setRateLimits merges comma-separated path=rate:burst entries into the
+// configured limits, e.g. "/wish/text=1:10,/api/v1/wish=5:50".
+func setRateLimits(c *config, value string) error {
+	limits := make(rateLimits, len(c.RateLimits))
+	for path, limit := range c.RateLimits {
+		limits[path] = limit
+	}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		path, spec, ok := strings.Cut(entry, "=")
+		rate, burst, ok2 := strings.Cut(spec, ":")
+		if !ok || !ok2 {
+			return fmt.Errorf("%q: want path=rate:burst", entry)
+		}
+		r, err := strconv.ParseFloat(rate, 64)
+		if err != nil {
+			return fmt.Errorf("%q: %w", entry, err)
+		}
+		b, err := strconv.Atoi(burst)
+		if err != nil {
+			return fmt.Errorf("%q: %w", entry, err)
+		}
+		limits[path] = rateLimit{Rate: r, Burst: b}
+	}
+	c.RateLimits = limits
+	return nil
+}
+
+var settings = []setting{
+	{"listen", "address to listen on, e.g. :6054", stringSetting(func(c *config) *string { return &c.Listen }), false},
+	{"admin-listen", "separate address for /metrics, e.g. 127.0.0.1:9090 (default: served on -listen)", stringSetting(func(c *config) *string { return &c.AdminListen }), false},
+	{"public-url", "fixed base URL of every generated link (default: the request's scheme and host)", stringSetting(func(c *config) *string { return &c.PublicURL }), false},
+	{"public-hosts", "comma-separated host names links may use; other hosts are replaced by the first", listSetting(func(c *config) *[]string { return &c.PublicHosts }), false},
+	{"image-backend", `greeting card backend: "local" or the URL of an external image service`, stringSetting(func(c *config) *string { return &c.ImageBackend }), false},
+	{"quotes", "YAML or JSON file with extra quotes", stringSetting(func(c *config) *string { return &c.QuotesFile }), false},
+	{"templates", "directory with HTML templates overriding the embedded ones", stringSetting(func(c *config) *string { return &c.TemplatesDir }), false},
+	{"read-timeout", "maximum duration for reading a request", durationSetting(func(c *config) *time.Duration { return &c.ReadTimeout }), false},
+	{"read-header-timeout", "maximum duration for reading request headers", durationSetting(func(c *config) *time.Duration { return &c.ReadHeaderTimeout }), false},
+	{"write-timeout", "maximum duration before timing out writes of a response", durationSetting(func(c *config) *time.Duration { return &c.WriteTimeout }), false},
+	{"idle-timeout", "maximum time to wait for the next request on a keep-alive connection", durationSetting(func(c *config) *time.Duration { return &c.IdleTimeout }), false},
+	{"shutdown-timeout", "how long to wait for in-flight requests on shutdown", durationSetting(func(c *config) *time.Duration { return &c.ShutdownTimeout }), false},
+	{"shutdown-delay", "how long to keep serving with /readyz failing before shutting down", durationSetting(func(c *config) *time.Duration { return &c.ShutdownDelay }), false},
+	{"live-max-duration", "how long an animated /wish/live greeting streams at most", durationSetting(func(c *config) *time.Duration { return &c.LiveMaxDuration }), false},
+	{"max-header-bytes", "maximum size of request headers in bytes", intSetting(func(c *config) *int { return &c.MaxHeaderBytes }), false},
+	{"log-format", `access log format: "text" or "json"`, stringSetting(func(c *config) *string { return &c.LogFormat }), false},
+	{"trusted-proxies", "comma-separated CIDRs of reverse proxies whose Forwarded and X-Forwarded-* headers are trusted", listSetting(func(c *config) *[]string { return &c.TrustedProxies }), false},
+	{"rate-limit", "comma-separated per-route limits as path=rate:burst, rate in requests per second", setRateLimits, false},
+	{"transliterate-slugs", "use ASCII slugs in share links, romanizing non-Latin names", boolSetting(func(c *config) *bool { return &c.TransliterateSlugs }), true},
+	{"content-security-policy", `Content-Security-Policy header, "{nonce}" is replaced per request (empty: none)`, stringSetting(func(c *config) *string { return &c.SecurityHeaders.ContentSecurityPolicy }), false},
+	{"csp-report-only", "send the policy as Content-Security-Policy-Report-Only, reporting without blocking", boolSetting(func(c *config) *bool { return &c.SecurityHeaders.CSPReportOnly }), true},
+	{"referrer-policy", "Referrer-Policy header (empty: none)", stringSetting(func(c *config) *string { return &c.SecurityHeaders.ReferrerPolicy }), false},
+	{"permissions-policy", "Permissions-Policy header (empty: none)", stringSetting(func(c *config) *string { return &c.SecurityHeaders.PermissionsPolicy }), false},
+	{"frame-options", `X-Frame-Options header: "DENY", "SAMEORIGIN" or empty for none`, stringSetting(func(c *config) *string { return &c.SecurityHeaders.FrameOptions }), false},
+	{"hsts", "Strict-Transport-Security header, only sent over TLS (empty: none)", stringSetting(func(c *config) *string { return &c.SecurityHeaders.HSTS }), false},
+	{"feature-image", "serve the local PNG greeting card", boolSetting(func(c *config) *bool { return &c.Features.Image }), true},
+	{"feature-svg", "serve SVG greetings", boolSetting(func(c *config) *bool { return &c.Features.SVG }), true},
+	{"feature-api", "serve the JSON API", boolSetting(func(c *config) *bool { return &c.Features.API }), true},
+	{"feature-rate-limit", "limit requests per client on the wish routes", boolSetting(func(c *config) *bool { return &c.Features.RateLimit }), true},
+	{"feature-metrics", "serve Prometheus metrics at /metrics", boolSetting(func(c *config) *bool { return &c.Features.Metrics }), true},
+	{"feature-color", "color plain text wishes for terminal clients", boolSetting(func(c *config) *bool { return &c.Features.Color }), true},
+	{"feature-live", "stream animated greetings at /wish/live", boolSetting(func(c *config) *bool { return &c.Features.Live }), true},
+}
+
+// loadConfig builds the effective configuration from the config file,
+// environment and command-line arguments.
+func loadConfig(args []string, getenv func(string) string) (config, bool, error) {
+	fs := flag.NewFlagSet("wish", flag.ContinueOnError)
+	configFile := fs.String("config", getenv("WISH_CONFIG"), "YAML config file (env WISH_CONFIG)")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration and exit")
+
+	type flagValue struct {
+		setting setting
+		value   string
+	}
+	var fromFlags []flagValue
+	for _, s := range settings {
+		record := func(value string) error {
+			fromFlags = append(fromFlags, flagValue{s, value})
+			return nil
+		}
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env())
+		if s.isBool {
+			fs.BoolFunc(s.flag, usage, record)
+		} else {
+			fs.Func(s.flag, usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return config{}, false, err
+	}
+
+	c := defaultConfig()
+	if *configFile != "" {
+		f, err := os.Open(*configFile)
+		if err != nil {
+			return config{}, false, fmt.Errorf("config file: %w", err)
+		}
+		err = decodeConfig(f, &c)
+		f.Close()
+		if err != nil {
+			return config{}, false, fmt.Errorf("config file %s: %w", *configFile, err)
+		}
+	}
+
+	for _, s := range settings {
+		if value := getenv(s.env()); value != "" {
+			if err := s.set(&c, value); err != nil {
+				return config{}, false, fmt.Errorf("%s: %w", s.env(), err)
+			}
+		}
+	}
+
+	for _, fv := range fromFlags {
+		if err := fv.setting.set(&c, fv.value); err != nil {
+			return config{}, false, fmt.Errorf("-%s: %w", fv.setting.flag, err)
+		}
+	}
+
+	return c, *printConfig, c.validate()
+}
+
+func decodeConfig(r io.Reader, c *config) error {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// validate reports the first invalid setting.
+func (c config) validate() error {
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	if c.AdminListen != "" {
+		if _, _, err := net.SplitHostPort(c.AdminListen); err != nil {
+			return fmt.Errorf("admin_listen: %w", err)
+		}
+		if c.AdminListen == c.Listen {
+			return errors.New("admin_listen: must differ from listen")
+		}
+	}
+	if c.PublicURL != "" {
+		if err := validateAbsoluteURL(c.PublicURL); err != nil {
+			return fmt.Errorf("public_url: %w", err)
+		}
+	}
+	for _, host := range c.PublicHosts {
+		if !validHost(host) {
+			return fmt.Errorf("public_hosts: %q is not a host name", host)
+		}
+	}
+	if c.ImageBackend != imageBackendLocal {
+		if err := validateAbsoluteURL(c.ImageBackend); err != nil {
+			return fmt.Errorf(`image_backend: must be "local" or an absolute URL: %w`, err)
+		}
+	}
+	if c.ImageBackend == imageBackendLocal && !c.Features.Image {
+		return errors.New(`image_backend "local" requires the image feature`)
+	}
+	timeouts := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"read_timeout", c.ReadTimeout},
+		{"read_header_timeout", c.ReadHeaderTimeout},
+		{"write_timeout", c.WriteTimeout},
+		{"idle_timeout", c.IdleTimeout},
+		{"shutdown_timeout", c.ShutdownTimeout},
+		{"shutdown_delay", c.ShutdownDelay},
+	}
+	for _, t := range timeouts {
+		if t.d < 0 {
+			return fmt.Errorf("%s: must not be negative", t.name)
+		}
+	}
+	if c.Features.Live && c.LiveMaxDuration <= 0 {
+		return errors.New("live_max_duration: must be positive")
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf(`log_format: must be "text" or "json", got %q`, c.LogFormat)
+	}
+	if c.MaxHeaderBytes < 0 {
+		return errors.New("max_header_bytes: must not be negative")
+	}
+	if _, err := parseTrustedProxies(c.TrustedProxies); err != nil {
+		return fmt.Errorf("trusted_proxies: %w", err)
+	}
+	switch c.SecurityHeaders.FrameOptions {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		return fmt.Errorf(`security_headers.frame_options: must be "DENY", "SAMEORIGIN" or empty, got %q`, c.SecurityHeaders.FrameOptions)
+	}
+	if hsts := c.SecurityHeaders.HSTS; hsts != "" && !strings.HasPrefix(strings.ToLower(hsts), "max-age=") {
+		return fmt.Errorf("security_headers.hsts: must start with max-age=, got %q", hsts)
+	}
+	for path, limit := range c.RateLimits {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("rate_limits: route %q must start with /", path)
+		}
+		if limit.Rate <= 0 || limit.Burst < 1 {
+			return fmt.Errorf("rate_limits %s: rate must be positive and burst at least 1", path)
+		}
+	}
+	return nil
+}
+
+func validateAbsoluteURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute http(s) URL", raw)
+	}
+	return nil
+}
+
+// dump renders the configuration as YAML.
+func (c config) dump() string {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	enc.Encode(c)
+	enc.Close()
+	return buf.String()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := "listen: \":7000\"\nlog_format: json\nread_timeout: 3s\nrate_limits:\n  /wish/text: {rate: 1, burst: 2}\n"
+	if err := os.WriteFile(file, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"WISH_CONFIG":       file,
+		"WISH_LISTEN":       ":8000",
+		"WISH_FEATURE_SVG":  "false",
+		"WISH_READ_TIMEOUT": "4s",
+	}
+
+	c, printConfig, err := loadConfig([]string{"-listen", ":9000"}, func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if printConfig {
+		t.Error("printConfig = true without -print-config")
+	}
+	if c.Listen != ":9000" {
+		t.Errorf("Listen = %q, want the flag value", c.Listen)
+	}
+	if c.ReadTimeout != 4*time.Second {
+		t.Errorf("ReadTimeout = %s, want the environment value", c.ReadTimeout)
+	}
+	if c.LogFormat != "json" {
+		t.Errorf("LogFormat = %q, want the file value", c.LogFormat)
+	}
+	if c.Features.SVG {
+		t.Error("Features.SVG = true, want false from the environment")
+	}
+	if got := c.RateLimits["/wish/text"]; got != (rateLimit{Rate: 1, Burst: 2}) {
+		t.Errorf("rate limit for /wish/text = %+v, want the file value", got)
+	}
+	if got := c.RateLimits["/wish/web"]; got != defaultConfig().RateLimits["/wish/web"] {
+		t.Errorf("rate limit for /wish/web = %+v, want the default kept", got)
+	}
+}
+
+func TestLoadConfigBoolFlag(t *testing.T) {
+	c, _, err := loadConfig([]string{"-feature-api=false", "-feature-image"}, func(string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Features.API || !c.Features.Image {
+		t.Errorf("Features = %+v, want api off and image on", c.Features)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-listen", "nope"}, "listen"},
+		{[]string{"-public-url", "wish.example"}, "public_url"},
+		{[]string{"-image-backend", "ftp://img"}, "image_backend"},
+		{[]string{"-read-timeout", "-1s"}, "read_timeout"},
+		{[]string{"-read-timeout", "soon"}, "-read-timeout"},
+		{[]string{"-log-format", "xml"}, "log_format"},
+		{[]string{"-trusted-proxies", "10.0.0.0/33"}, "trusted_proxies"},
+		{[]string{"-public-hosts", "wish.example/path"}, "public_hosts"},
+		{[]string{"-rate-limit", "/wish=0:5"}, "rate_limits"},
+		{[]string{"-rate-limit", "/wish"}, "-rate-limit"},
+		{[]string{"-admin-listen", ":6054"}, "admin_listen"},
+		{[]string{"-feature-image=false"}, "image feature"},
+		{[]string{"-frame-options", "ALLOW"}, "frame_options"},
+		{[]string{"-hsts", "1 year"}, "hsts"},
+		{[]string{"-live-max-duration", "0s"}, "live_max_duration"},
+	}
+	for _, tt := range tests {
+		_, _, err := loadConfig(tt.args, func(string) string { return "" })
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("loadConfig(%q) error = %v, want it to mention %q", tt.args, err, tt.want)
+		}
+	}
+}
+
+func TestSecurityHeaderErrorsNameTheirSetting(t *testing.T) {
+	args := []string{"-frame-options", "ALLOW", "-rate-limit", "/wish=1:2"}
+	_, _, err := loadConfig(args, func(string) string { return "" })
+	if err == nil || strings.Contains(err.Error(), "rate-limit") {
+		t.Errorf("loadConfig(%q) error = %v, want a frame_options error not blamed on -rate-limit", args, err)
+	}
+}
+
+func TestLoadConfigUnknownFileKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte("listne: \":7000\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := loadConfig([]string{"-config", file}, func(string) string { return "" }); err == nil {
+		t.Error("loadConfig accepted a misspelled key")
+	}
+}
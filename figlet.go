@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed fonts/*.flf
+var embeddedFonts embed.FS
+
+const (
+	defaultFont = "standard"
+	// figletWidth is the column budget a rendered line is wrapped to.
+	figletWidth = 80
+)
+
+// Horizontal layout bits of a FIGfont's full_layout header field.
+const (
+	smushEqual     = 1
+	smushLowline   = 2
+	smushHierarchy = 4
+	smushPair      = 8
+	smushBigX      = 16
+	smushHardblank = 32
+	layoutKern     = 64
+	layoutSmush    = 128
+)
+
+var errUnknownFont = errors.New("unknown font")
+
+// fonts holds the bundled FIGlet fonts keyed by file name without extension.
+var fonts = mustLoadFonts()
+
+// figFont is a parsed FIGlet (.flf) font.
+type figFont struct {
+	name      string
+	hardblank rune
+	height    int
+	layout    int
+	chars     map[rune][]string
+}
+
+// deutschCodes are the code points of the seven characters that follow the
+// printable ASCII range in every FIGfont.
+var deutschCodes = []rune{196, 214, 220, 228, 246, 252, 223}
+
+// parseFont reads a FIGfont in the flf2a format.
+func parseFont(name string, r io.Reader) (*figFont, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	if !sc.Scan() {
+		return nil, fmt.Errorf("font %s: missing header", name)
+	}
+	header := strings.Fields(sc.Text())
+	if len(header) < 6 || !strings.HasPrefix(header[0], "flf2a") || len(header[0]) < 6 {
+		return nil, fmt.Errorf("font %s: invalid header", name)
+	}
+
+	nums := make([]int, len(header)-1)
+	for i, field := range header[1:] {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("font %s: invalid header field %q", name, field)
+		}
+		nums[i] = n
+	}
+
+	f := &figFont{
+		name:      name,
+		hardblank: []rune(header[0])[5],
+		height:    nums[0],
+		chars:     make(map[rune][]string),
+	}
+	if f.height < 1 {
+		return nil, fmt.Errorf("font %s: invalid height %d", name, f.height)
+	}
+
+	oldLayout := nums[3]
+	switch {
+	case len(nums) >= 7:
+		f.layout = nums[6]
+	case oldLayout < 0:
+		f.layout = 0
+	case oldLayout == 0:
+		f.layout = layoutKern
+	default:
+		f.layout = oldLayout&63 | layoutSmush
+	}
+
+	for i := 0; i < nums[4]; i++ {
+		if !sc.Scan() {
+			return nil, fmt.Errorf("font %s: truncated comments", name)
+		}
+	}
+
+	readChar := func() ([]string, bool) {
+		rows := make([]string, f.height)
+		for i := range rows {
+			if !sc.Scan() {
+				return nil, false
+			}
+			line := strings.TrimRight(sc.Text(), "\r")
+			if line != "" {
+				endmark := line[len(line)-1:]
+				line = strings.TrimRight(line, endmark)
+			}
+			rows[i] = line
+		}
+		return rows, true
+	}
+
+	for code := rune(32); code <= 126; code++ {
+		rows, ok := readChar()
+		if !ok {
+			return nil, fmt.Errorf("font %s: truncated at character %d", name, code)
+		}
+		f.chars[code] = rows
+	}
+
+	for _, code := range deutschCodes {
+		rows, ok := readChar()
+		if !ok {
+			return f, nil
+		}
+		f.chars[code] = rows
+	}
+
+	for sc.Scan() {
+		tag := strings.Fields(sc.Text())
+		if len(tag) == 0 {
+			continue
+		}
+		code, err := strconv.ParseInt(tag[0], 0, 64)
+		rows, ok := readChar()
+		if !ok {
+			break
+		}
+		if err == nil && code >= 0 {
+			f.chars[rune(code)] = rows
+		}
+	}
+	return f, sc.Err()
+}
+
+func loadFonts(fsys fs.FS) (map[string]*figFont, error) {
+	files, err := fs.Glob(fsys, "fonts/*.flf")
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[string]*figFont, len(files))
+	for _, file := range files {
+		r, err := fsys.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(path.Base(file), ".flf")
+		f, err := parseFont(name, r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		loaded[name] = f
+	}
+	return loaded, nil
+}
+
+func mustLoadFonts() map[string]*figFont {
+	f, err := loadFonts(embeddedFonts)
+	if err != nil {
+		log.Fatalf("Failed to load FIGlet fonts: %v", err)
+	}
+	return f
+}
+
+// lookupFont returns the named bundled font, or the default when name is empty.
+func lookupFont(name string) (*figFont, error) {
+	if name == "" {
+		name = defaultFont
+	}
+	f, ok := fonts[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w %q (available: %s)", errUnknownFont, name, strings.Join(fontNames(), ", "))
+	}
+	return f, nil
+}
+
+// fontNames returns the names of the bundled fonts in sorted order.
+func fontNames() []string {
+	names := make([]string, 0, len(fonts))
+	for name := range fonts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// glyph returns the rows for r. Characters the font lacks fall back to the
+// font's missing-character glyph (code 0) when it defines one, and are
+// otherwise dropped.
+func (f *figFont) glyph(r rune) ([]string, bool) {
+	if rows, ok := f.chars[r]; ok {
+		return rows, true
+	}
+	rows, ok := f.chars[0]
+	return rows, ok
+}
+
+// Render draws text in the font, wrapping on spaces so no line is wider
+// than width columns. It returns "" when none of the characters can be drawn.
+func (f *figFont) Render(text string, width int) string {
+	var blocks [][][]rune
+	var current string
+	var currentRows [][]rune
+
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		rows := f.renderLine(candidate)
+		if current != "" && rowsWidth(rows) > width {
+			blocks = append(blocks, currentRows)
+			candidate, rows = word, f.renderLine(word)
+		}
+		current, currentRows = candidate, rows
+	}
+	if current != "" {
+		blocks = append(blocks, currentRows)
+	}
+
+	var b strings.Builder
+	for _, rows := range blocks {
+		if rowsWidth(rows) == 0 {
+			continue
+		}
+		for _, row := range rows {
+			line := strings.ReplaceAll(string(row), string(f.hardblank), " ")
+			b.WriteString(strings.TrimRight(line, " "))
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+func rowsWidth(rows [][]rune) int {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	return width
+}
+
+// renderLine lays out text on a single line using the font's horizontal
+// layout (full width, kerning or controlled smushing).
+func (f *figFont) renderLine(text string) [][]rune {
+	out := make([][]rune, f.height)
+	prevWidth := 0
+	for _, r := range text {
+		rows, ok := f.glyph(r)
+		if !ok {
+			continue
+		}
+		glyph := make([][]rune, f.height)
+		charWidth := 0
+		for i := range glyph {
+			glyph[i] = []rune(rows[i])
+			charWidth = max(charWidth, len(glyph[i]))
+		}
+		for i := range glyph {
+			for len(glyph[i]) < charWidth {
+				glyph[i] = append(glyph[i], ' ')
+			}
+		}
+
+		amount := f.smushAmount(out, glyph, prevWidth, charWidth)
+		for i := range out {
+			lineLen := len(out[i])
+			for k := 0; k < amount && k < charWidth; k++ {
+				col := lineLen - amount + k
+				if col < 0 {
+					continue
+				}
+				out[i][col] = f.smush(out[i][col], glyph[i][k], prevWidth, charWidth)
+			}
+			if amount < charWidth {
+				out[i] = append(out[i], glyph[i][amount:]...)
+			}
+		}
+		prevWidth = charWidth
+	}
+	return out
+}
+
+// smushAmount returns how many columns the next glyph may overlap the
+// current output, following the reference FIGlet implementation.
+func (f *figFont) smushAmount(out, glyph [][]rune, prevWidth, charWidth int) int {
+	if f.layout&(layoutKern|layoutSmush) == 0 {
+		return 0
+	}
+
+	amount := charWidth
+	for i := range out {
+		line := out[i]
+		lineBound := len(line)
+		var left rune
+		for {
+			left = 0
+			if lineBound < len(line) {
+				left = line[lineBound]
+			}
+			if lineBound == 0 || (left != 0 && left != ' ') {
+				break
+			}
+			lineBound--
+		}
+		charBound := 0
+		for charBound < len(glyph[i]) && glyph[i][charBound] == ' ' {
+			charBound++
+		}
+
+		rowAmount := charBound + len(line) - 1 - lineBound
+		switch {
+		case left == 0 || left == ' ':
+			rowAmount++
+		case charBound < len(glyph[i]):
+			if f.smush(left, glyph[i][charBound], prevWidth, charWidth) != 0 {
+				rowAmount++
+			}
+		}
+		amount = min(amount, rowAmount)
+	}
+	return max(amount, 0)
+}
+
+// smush merges two overlapping characters, returning 0 when they cannot be
+// combined under the font's rules.
+func (f *figFont) smush(left, right rune, prevWidth, charWidth int) rune {
+	if left == ' ' {
+		return right
+	}
+	if right == ' ' {
+		return left
+	}
+	if prevWidth < 2 || charWidth < 2 {
+		return 0
+	}
+	if f.layout&layoutSmush == 0 {
+		return 0
+	}
+
+	rules := f.layout & 63
+	if rules == 0 {
+		if left == f.hardblank {
+			return right
+		}
+		if right == f.hardblank {
+			return left
+		}
+		return right
+	}
+
+	if rules&smushHardblank != 0 && left == f.hardblank && right == f.hardblank {
+		return left
+	}
+	if left == f.hardblank || right == f.hardblank {
+		return 0
+	}
+	if rules&smushEqual != 0 && left == right {
+		return left
+	}
+	if rules&smushLowline != 0 {
+		if left == '_' && strings.ContainsRune(`|/\[]{}()<>`, right) {
+			return right
+		}
+		if right == '_' && strings.ContainsRune(`|/\[]{}()<>`, left) {
+			return left
+		}
+	}
+	if rules&smushHierarchy != 0 {
+		classes := []string{"|", `/\`, "[]", "{}", "()", "<>"}
+		for i, class := range classes {
+			if strings.ContainsRune(class, left) && strings.ContainsRune(strings.Join(classes[i+1:], ""), right) {
+				return right
+			}
+			if strings.ContainsRune(class, right) && strings.ContainsRune(strings.Join(classes[i+1:], ""), left) {
+				return left
+			}
+		}
+	}
+	if rules&smushPair != 0 {
+		switch string([]rune{left, right}) {
+		case "[]", "][", "{}", "}{", "()", ")(":
+			return '|'
+		}
+	}
+	if rules&smushBigX != 0 {
+		switch string([]rune{left, right}) {
+		case `/\`:
+			return '|'
+		case `\/`:
+			return 'Y'
+		case "><":
+			return 'X'
+		}
+	}
+	return 0
+}
@@ -1,3 +1,10 @@
 module github.com/sanwebinfo/friendship-day-wishes
 
 go 1.24.5
+
+require (
+	github.com/rivo/uniseg v0.4.7
+	golang.org/x/image v0.36.0
+	golang.org/x/text v0.34.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// greeting is the format-neutral result of rendering a wish. Every field
+// holds plain, unescaped text; each output format escapes it exactly once
+// as it writes the greeting out:
+//
+//   - text and ANSI: no escaping, only terminal control characters are dropped
+//   - HTML: html/template's contextual auto-escaping
+//   - JSON: encoding/json string encoding
+//   - SVG: XML escaping of text and attribute values (xmlEscape)
+type greeting struct {
+	Name  string
+	Slug  string
+	Art   string
+	Quote quote
+}
+
+// newGreeting renders the wish for a validated name with the given style.
+// Handlers call it once every parameter is validated, so only greetings
+// actually served count towards wish_style_total.
+func newGreeting(validName string, style artRenderer, opts artOptions) greeting {
+	if opts.Style != "" {
+		styleUsage.inc(opts.Style)
+	}
+	return greeting{
+		Name:  cleanName(validName),
+		Slug:  slugFor(validName, cfg.TransliterateSlugs),
+		Art:   style.Render(validName, opts),
+		Quote: opts.Quote,
+	}
+}
+
+// terminal lays the greeting out as a shell session: a prompt with the
+// name, the art block and the indented quote. The result is still plain
+// text, shown as is by the text format and inside <pre> by the HTML page.
+func (g greeting) terminal() string {
+	return fmt.Sprintf("\n wishes@%s:~💚$%s\n%s", g.Name, g.Art, indentLines(g.Quote.Text, " "))
+}
+
+// stripControls drops control characters other than newlines and tabs, so
+// text written to a terminal cannot carry escape sequences. Names never
+// contain them, but quotes loaded from a file might.
+func stripControls(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, s)
+}
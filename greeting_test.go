@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// specialName contains every character that HTML, XML or JSON escape.
+const specialName = `Tom & Jerry's <"Gang">`
+
+func wishFormat(t *testing.T, format, style string) string {
+	t.Helper()
+	query := url.Values{"name": {specialName}, "format": {format}, "style": {style}}
+	rec := doRequest(t, http.MethodGet, "/wish?"+query.Encode(), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("format %s: status = %d, want 200; body: %s", format, rec.Code, rec.Body)
+	}
+	return rec.Body.String()
+}
+
+func TestTextIsNotEscaped(t *testing.T) {
+	for _, style := range []string{"friend", "heart", "card", "cowsay"} {
+		body := wishFormat(t, "text", style)
+		if style != "friend" && !strings.Contains(body, specialName) {
+			t.Errorf("style %s: text does not contain the name verbatim:\n%s", style, body)
+		}
+		if !strings.Contains(body, "wishes@"+specialName+":~") {
+			t.Errorf("style %s: prompt does not contain the name verbatim:\n%s", style, body)
+		}
+		if strings.Contains(body, "&amp;") || strings.Contains(body, "&lt;") {
+			t.Errorf("style %s: text contains HTML entities:\n%s", style, body)
+		}
+	}
+}
+
+func TestHTMLIsEscapedOnce(t *testing.T) {
+	body := wishFormat(t, "html", "card")
+	escaped := `Tom &amp; Jerry&#39;s &lt;&#34;Gang&#34;&gt;`
+	if !strings.Contains(body, "wishes@"+escaped+":~") {
+		t.Errorf("prompt is not escaped exactly once, want %s", escaped)
+	}
+	if !strings.Contains(body, "<title>"+escaped+" : Happy Friendship Wishes</title>") {
+		t.Errorf("title is not escaped exactly once, want %s", escaped)
+	}
+	if strings.Contains(body, "&amp;amp;") || strings.Contains(body, "&amp;lt;") {
+		t.Error("page contains double-escaped entities")
+	}
+	if strings.Contains(body, specialName) {
+		t.Error("page contains the name unescaped")
+	}
+}
+
+func TestJSONIsNotHTMLEscaped(t *testing.T) {
+	var got apiWish
+	if err := json.Unmarshal([]byte(wishFormat(t, "json", "card")), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != specialName {
+		t.Errorf("name = %q, want %q", got.Name, specialName)
+	}
+	if !strings.Contains(got.Art, specialName) {
+		t.Errorf("art does not contain the name verbatim:\n%s", got.Art)
+	}
+}
+
+func TestSVGIsEscapedOnce(t *testing.T) {
+	body := wishFormat(t, "svg", "card")
+	var texts []string
+	dec := xml.NewDecoder(strings.NewReader(body))
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("SVG is not well-formed: %v\n%s", err, body)
+		}
+		if cd, ok := tok.(xml.CharData); ok {
+			texts = append(texts, string(cd))
+		}
+	}
+	if all := strings.Join(texts, "\n"); !strings.Contains(all, "Happy Friendship Day, "+specialName+"!") || strings.Count(all, specialName) < 3 {
+		t.Errorf("decoded SVG text does not contain the name verbatim:\n%s", all)
+	}
+	if strings.Contains(body, "&amp;amp;") {
+		t.Error("SVG contains double-escaped entities")
+	}
+}
+
+func TestStripControls(t *testing.T) {
+	in := "line one\n\tline \x1b[31mtwo\x07\u0085" + specialName
+	want := "line one\n\tline [31mtwo" + specialName
+	if got := stripControls(in); got != want {
+		t.Errorf("stripControls(%q) = %q, want %q", in, got, want)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// shuttingDown is set once graceful shutdown starts so /readyz tells load
+// balancers to stop sending traffic while in-flight requests drain.
+var shuttingDown atomic.Bool
+
+// buildTime may be set at link time with -ldflags "-X main.buildTime=...".
+var buildTime string
+
+// requiredPages are the templates every deployment must provide.
+var requiredPages = []string{"home", "wish", "404", "500"}
+
+// readinessCheck is one condition /readyz reports on.
+type readinessCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+func check(name string, err error) readinessCheck {
+	c := readinessCheck{Name: name, OK: err == nil}
+	if err != nil {
+		c.Error = err.Error()
+	}
+	return c
+}
+
+// readinessChecks verifies the loaded quotes, fonts and templates and that
+// the server is not shutting down.
+func readinessChecks() []readinessCheck {
+	var quotesErr, fontsErr, templatesErr, shutdownErr error
+	if len(quotes) == 0 {
+		quotesErr = errors.New("no quotes loaded")
+	}
+	if _, ok := fonts[defaultFont]; !ok {
+		fontsErr = fmt.Errorf("default font %q not loaded", defaultFont)
+	}
+	for _, name := range requiredPages {
+		if pages[name] == nil {
+			templatesErr = fmt.Errorf("template %q not loaded", name)
+			break
+		}
+	}
+	if shuttingDown.Load() {
+		shutdownErr = errors.New("shutting down")
+	}
+	return []readinessCheck{
+		check("quotes", quotesErr),
+		check("fonts", fontsErr),
+		check("templates", templatesErr),
+		check("shutdown", shutdownErr),
+	}
+}
+
+// healthzHandler reports that the process is alive.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	setTextHeaders(w)
+	fmt.Fprintln(w, "ok")
+}
+
+// readyzHandler reports whether the server can take traffic, answering 503
+// with the failing checks otherwise.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	checks := readinessChecks()
+	status := http.StatusOK
+	for _, c := range checks {
+		if !c.OK {
+			status = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, map[string]any{
+		"ready":  status == http.StatusOK,
+		"checks": checks,
+	})
+}
+
+// versionInfo describes the running build.
+type versionInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	VCSTime   string `json:"vcs_time,omitempty"`
+	Modified  bool   `json:"modified"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// readVersion collects version details from the embedded build info.
+func readVersion() versionInfo {
+	v := versionInfo{Version: "(devel)", BuildTime: buildTime, GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	if info.Main.Version != "" {
+		v.Version = info.Main.Version
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			v.Revision = s.Value
+		case "vcs.time":
+			v.VCSTime = s.Value
+		case "vcs.modified":
+			v.Modified = s.Value == "true"
+		}
+	}
+	return v
+}
+
+var version = readVersion()
+
+// versionHandler reports the module version, VCS revision and build time.
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, version)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"testing"
+)
+
+func TestReadyzFlipsDuringShutdown(t *testing.T) {
+	if rec := doRequest(t, http.MethodGet, "/readyz", nil); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+
+	shuttingDown.Store(true)
+	t.Cleanup(func() { shuttingDown.Store(false) })
+	rec := doRequest(t, http.MethodGet, "/readyz", nil)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status during shutdown = %d, want 503", rec.Code)
+	}
+	var body struct {
+		Ready bool `json:"ready"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Ready {
+		t.Errorf("body = %s, want ready false", rec.Body)
+	}
+}
+
+func TestReadyzReportsMissingTemplates(t *testing.T) {
+	saved := pages
+	t.Cleanup(func() { pages = saved })
+	pages = map[string]*template.Template{}
+	for _, c := range readinessChecks() {
+		if c.Name == "templates" && c.OK {
+			t.Error("templates check passed without templates")
+		}
+	}
+}
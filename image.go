@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// cardSize is the width and height of the rendered greeting card.
+const cardSize = 1080
+
+var (
+	cardBoldFont    = mustParseFont(gobold.TTF)
+	cardRegularFont = mustParseFont(goregular.TTF)
+
+	cardTopColor    = color.RGBA{0x58, 0xB1, 0x9F, 0xff}
+	cardBottomColor = color.RGBA{0x2C, 0x7A, 0x6B, 0xff}
+	cardPanelColor  = color.RGBA{0xD6, 0xA2, 0xE8, 0xff}
+	cardTextColor   = color.RGBA{0x2C, 0x3A, 0x47, 0xff}
+	cardNameColor   = color.RGBA{0x6D, 0x21, 0x4F, 0xff}
+	cardAccentColor = color.RGBA{0xFD, 0x72, 0x72, 0xff}
+)
+
+func mustParseFont(ttf []byte) *opentype.Font {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		log.Fatalf("Failed to parse card font: %v", err)
+	}
+	return f
+}
+
+func newFace(f *opentype.Font, size float64) font.Face {
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		log.Fatalf("Failed to create font face: %v", err)
+	}
+	return face
+}
+
+// fitFace returns the largest face, starting at size, in which text is no
+// wider than maxWidth pixels.
+func fitFace(f *opentype.Font, text string, size float64, maxWidth int) font.Face {
+	for ; size > 24; size -= 4 {
+		face := newFace(f, size)
+		if font.MeasureString(face, text).Ceil() <= maxWidth {
+			return face
+		}
+		face.Close()
+	}
+	return newFace(f, 24)
+}
+
+// drawCentered draws text horizontally centered with its baseline at y.
+func drawCentered(img draw.Image, face font.Face, c color.Color, text string, y int) {
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face}
+	width := d.MeasureString(text)
+	d.Dot = fixed.Point26_6{X: (fixed.I(img.Bounds().Dx()) - width) / 2, Y: fixed.I(y)}
+	d.DrawString(text)
+}
+
+// fillRoundedRect fills r with c, rounding its corners by radius pixels.
+func fillRoundedRect(img *image.RGBA, r image.Rectangle, radius int, c color.RGBA) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			cx := min(max(x, r.Min.X+radius), r.Max.X-radius-1)
+			cy := min(max(y, r.Min.Y+radius), r.Max.Y-radius-1)
+			if dx, dy := x-cx, y-cy; dx*dx+dy*dy <= radius*radius {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+// lerpColor blends a into b by t/total.
+func lerpColor(a, b color.RGBA, t, total int) color.RGBA {
+	mix := func(x, y uint8) uint8 { return uint8((int(x)*(total-t) + int(y)*t) / total) }
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 0xff}
+}
+
+// cardFallbackName greets names the card font cannot draw at all.
+const cardFallbackName = "Friend"
+
+// hasGlyphs reports whether f can draw every character of s.
+func hasGlyphs(f *opentype.Font, s string) bool {
+	var buf sfnt.Buffer
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if i, err := f.GlyphIndex(&buf, r); err != nil || i == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// cardName returns name as the card font can draw it. The Go fonts cover
+// Latin, Greek and Cyrillic; a word in another script is romanized where
+// transliterate knows it ("அருண்" becomes "Arun"), characters still
+// without a glyph, such as Han or emoji, are dropped, and a name with
+// nothing left becomes cardFallbackName.
+func cardName(name string) string {
+	var words []string
+	for _, word := range strings.Fields(name) {
+		if !hasGlyphs(cardBoldFont, word) {
+			word = strings.Map(func(r rune) rune {
+				if !hasGlyphs(cardBoldFont, string(r)) {
+					return -1
+				}
+				return r
+			}, transliterate(word))
+			if r := []rune(word); len(r) > 0 {
+				word = string(unicode.ToUpper(r[0])) + string(r[1:])
+			}
+		}
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return cardFallbackName
+	}
+	return strings.Join(words, " ")
+}
+
+// renderCard draws the square greeting card for name with its quote.
+func renderCard(name string, q quote) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, cardSize, cardSize))
+	for y := 0; y < cardSize; y++ {
+		draw.Draw(img, image.Rect(0, y, cardSize, y+1), image.NewUniform(lerpColor(cardTopColor, cardBottomColor, y, cardSize)), image.Point{}, draw.Src)
+	}
+
+	fillRoundedRect(img, image.Rect(60, 60, cardSize-60, cardSize-60), 48, cardPanelColor)
+
+	heading := newFace(cardBoldFont, 64)
+	defer heading.Close()
+	drawCentered(img, heading, cardTextColor, "Happy Friendship Day", 220)
+
+	name = cardName(name)
+	nameFace := fitFace(cardBoldFont, name, 120, cardSize-240)
+	defer nameFace.Close()
+	drawCentered(img, nameFace, cardNameColor, name, 420)
+
+	fillRoundedRect(img, image.Rect(cardSize/2-120, 490, cardSize/2+120, 500), 5, cardAccentColor)
+
+	quoteFace := newFace(cardRegularFont, 44)
+	defer quoteFace.Close()
+	y := 600
+	for _, line := range strings.Split(q.Text, "\n") {
+		for _, wrapped := range wrapWords(line, 36) {
+			drawCentered(img, quoteFace, cardTextColor, wrapped, y)
+			y += 64
+		}
+	}
+
+	footer := newFace(cardRegularFont, 32)
+	defer footer.Close()
+	drawCentered(img, footer, cardTextColor, "You are a fantastic friend", cardSize-120)
+	return img
+}
+
+// wishImageHandler handles requests for the PNG greeting card.
+func wishImageHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	validName, err := validateName(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	q, err := quoteFromRequest(r, validName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, renderCard(cleanName(validName), q)); err != nil {
+		log.Printf("Failed to encode card: %v", err)
+		internalServerErrorHandler(w, r)
+		return
+	}
+
+	formatUsage.inc("png")
+	setPNGHeaders(w)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	if r.URL.Query().Has("download") {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": "friendship-day-" + slugFor(validName, cfg.TransliterateSlugs) + ".png",
+		}))
+	}
+	buf.WriteTo(w)
+}
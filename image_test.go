@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestCardName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Sam", "Sam"},
+		{"Zoë Ångström", "Zoë Ångström"},
+		{"Дмитрий", "Дмитрий"},
+		{"அருண்", "Arun"},
+		{"राम Kumar", "Ram Kumar"},
+		{"Sam 🎉", "Sam"},
+		{"さくら", cardFallbackName},
+		{"李", cardFallbackName},
+	}
+	for _, tt := range tests {
+		got := cardName(tt.name)
+		if got != tt.want {
+			t.Errorf("cardName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+		if !hasGlyphs(cardBoldFont, got) {
+			t.Errorf("cardName(%q) = %q has characters the card font cannot draw", tt.name, got)
+		}
+	}
+}
+
+func TestCardDrawsNonLatinName(t *testing.T) {
+	q := quoteFor("Arun")
+	if got, want := renderCard("அருண்", q), renderCard("Arun", q); !bytes.Equal(got.Pix, want.Pix) {
+		t.Error("card for அருண் differs from the card for its romanization Arun")
+	}
+
+	rec := doRequest(t, http.MethodGet, "/wish/image.png?name="+url.QueryEscape("அருண்"), nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("status = %d, Content-Type = %q; want a PNG", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
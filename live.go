@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rivo/uniseg"
+)
+
+// Terminal control sequences used by the live animation.
+const (
+	ansiHideCursor  = "\x1b[?25l"
+	ansiShowCursor  = "\x1b[?25h"
+	ansiClearScreen = "\x1b[2J"
+	ansiCursorHome  = "\x1b[H"
+	ansiClearLine   = "\x1b[K"
+	ansiClearBelow  = "\x1b[J"
+)
+
+// Frame timings of the live animation.
+const (
+	heartRowDelay    = 90 * time.Millisecond
+	heartHoldDelay   = 700 * time.Millisecond
+	sparkleDelay     = 150 * time.Millisecond
+	sparkleFrames    = 14
+	typingDelay      = 45 * time.Millisecond
+	finalHoldDelay   = 2500 * time.Millisecond
+	liveWriteTimeout = 10 * time.Second
+)
+
+// sparkles are drawn around the FANTASTIC FRIEND banner.
+var sparkles = []string{"✦", "✧", "*", "+", "·"}
+
+// frame is one screen of the live animation: art and quote laid out like
+// the text response, shown for delay.
+type frame struct {
+	art   string
+	quote string
+	delay time.Duration
+}
+
+// liveFrames builds the animation for g: the heart filling in from the
+// bottom, sparkles twinkling around the banner of the friend art, then
+// the quote typed out below it. Sparkles are placed by a generator seeded
+// with the name, so a name always gets the same animation.
+func liveFrames(g greeting, font *figFont) []frame {
+	art := greetingArt(g.Name, font)
+	var frames []frame
+	frames = append(frames, heartFill(heartStyle(g.Name, artOptions{}))...)
+	frames = append(frames, twinkle(art, nameSeed(g.Name))...)
+	frames = append(frames, typeOut(art, g.Quote.Text)...)
+	return frames
+}
+
+func nameSeed(name string) uint64 {
+	h := fnv.New64a()
+	io.WriteString(h, name)
+	return h.Sum64()
+}
+
+// heartFill reveals the rows of a heart from the bottom up. Hidden rows
+// stay as empty lines so the heart does not move while it fills.
+func heartFill(heart string) []frame {
+	rows := strings.Split(strings.Trim(heart, "\n"), "\n")
+	frames := make([]frame, 0, len(rows))
+	for shown := 1; shown <= len(rows); shown++ {
+		visible := make([]string, len(rows))
+		copy(visible[len(rows)-shown:], rows[len(rows)-shown:])
+		frames = append(frames, frame{art: "\n" + strings.Join(visible, "\n") + "\n", delay: heartRowDelay})
+	}
+	frames[len(frames)-1].delay = heartHoldDelay
+	return frames
+}
+
+// twinkle scatters sparkles right of the text on the banner line of art
+// and the lines around it. Sparkles only replace spaces, each one
+// column wide, so the art keeps its layout.
+func twinkle(art string, seed uint64) []frame {
+	rows := strings.Split(art, "\n")
+	banner := -1
+	for i, row := range rows {
+		if strings.Contains(row, "ANTASTIC FRIEND") {
+			banner = i
+		}
+	}
+	if banner < 0 {
+		return nil
+	}
+
+	width := displayWidth(rows[banner]) + 3
+	type cell struct{ row, col int }
+	var blanks []cell
+	grid := make([][]string, len(rows))
+	for i, row := range rows {
+		row = strings.TrimRight(row, " \t")
+		for _, r := range row {
+			grid[i] = append(grid[i], string(r))
+		}
+		if i < banner-1 || i > banner+1 {
+			continue
+		}
+		for len(grid[i]) < width {
+			grid[i] = append(grid[i], " ")
+		}
+		// Start right of the text, keeping the glyphs and the gaps
+		// between the banner's words free.
+		for col := len([]rune(row)) + 1; col < len(grid[i]); col++ {
+			if grid[i][col] == " " {
+				blanks = append(blanks, cell{i, col})
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed))
+	frames := make([]frame, sparkleFrames)
+	for n := range frames {
+		lit := make(map[cell]string)
+		for range max(len(blanks)/6, 1) {
+			lit[blanks[rng.IntN(len(blanks))]] = sparkles[rng.IntN(len(sparkles))]
+		}
+		lines := make([]string, len(grid))
+		for i, row := range grid {
+			var b strings.Builder
+			for col, s := range row {
+				if sparkle, ok := lit[cell{i, col}]; ok {
+					s = sparkle
+				}
+				b.WriteString(s)
+			}
+			lines[i] = strings.TrimRight(b.String(), " ")
+		}
+		frames[n] = frame{art: strings.Join(lines, "\n"), delay: sparkleDelay}
+	}
+	return frames
+}
+
+// typeOut types quote below art one character at a time, with a block
+// cursor after the last character, and holds the finished greeting.
+func typeOut(art, quote string) []frame {
+	var frames []frame
+	typed, rest, state := "", quote, -1
+	for rest != "" {
+		var cluster string
+		cluster, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		typed += cluster
+		if strings.TrimSpace(cluster) == "" {
+			continue
+		}
+		frames = append(frames, frame{art: art, quote: typed + "▌", delay: typingDelay})
+	}
+	return append(frames, frame{art: art, quote: quote, delay: finalHoldDelay})
+}
+
+// play shows frames in order, starting over after the last one, until
+// ctx is done or the server starts shutting down. It stops at the first
+// failed write.
+func play(ctx context.Context, frames []frame, show func(frame) error) {
+	if len(frames) == 0 {
+		return
+	}
+	for {
+		for _, f := range frames {
+			if err := show(f); err != nil {
+				return
+			}
+			t := time.NewTimer(f.delay)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return
+			case <-t.C:
+			}
+			if shuttingDown.Load() {
+				return
+			}
+		}
+	}
+}
+
+// screen returns the escape sequences drawing g with the art and quote of
+// f over the previous frame: the cursor moves home instead of clearing
+// the screen, which would flicker, and every line and the rest of the
+// screen are cleared behind the new text.
+func (g greeting) screen(f frame, colors ansiOptions, colored bool) string {
+	g.Art, g.Quote.Text = f.art, f.quote
+	text := g.terminal()
+	if colored {
+		text = g.ansi(colors)
+	}
+	return ansiCursorHome + strings.ReplaceAll(text, "\n", ansiClearLine+"\n") + ansiClearLine + ansiClearBelow
+}
+
+// wishLiveHandler streams the animated greeting to terminal clients until
+// the client disconnects or live_max_duration passes, and then leaves the
+// finished greeting on screen.
+func wishLiveHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	validName, err := validateName(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The animation draws its own heart and banner, so unlike the other
+	// wish routes it has no style to pick, or to count in wish_style_total.
+	if r.URL.Query().Has("style") {
+		http.Error(w, "style is not supported by the live animation", http.StatusBadRequest)
+		return
+	}
+	font, err := lookupFont(r.URL.Query().Get("font"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q, err := quoteFromRequest(r, validName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts := artOptions{Font: font, Quote: q}
+
+	colors, colored, err := colorFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Cursor control already assumes a terminal, so colors are on unless
+	// the request turns them off.
+	if query := r.URL.Query(); !query.Has("color") && !query.Has("no_color") {
+		colored = cfg.Features.Color
+	}
+
+	g := newGreeting(validName, artRendererFunc(friendStyle), opts)
+	g.Name, g.Art, g.Quote.Text = stripControls(g.Name), stripControls(g.Art), stripControls(g.Quote.Text)
+	webURL := shareURL(publicBaseURL(r), g.Slug)
+
+	formatUsage.inc("live")
+	setTextHeaders(w)
+	w.Header().Set("Cache-Control", "no-store")
+	// Ask buffering proxies such as nginx to pass frames through at once.
+	w.Header().Set("X-Accel-Buffering", "no")
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	write := func(s string) error {
+		// Extend the server's write timeout, which would otherwise cut
+		// long streams short. Writers without deadlines are fine too.
+		rc.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+		if _, err := io.WriteString(w, s); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	if write(ansiHideCursor+ansiClearScreen) != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), cfg.LiveMaxDuration)
+	defer cancel()
+	play(ctx, liveFrames(g, opts.Font), func(f frame) error {
+		return write(g.screen(f, colors, colored))
+	})
+	if r.Context().Err() != nil {
+		return
+	}
+
+	link := webURL
+	if colored {
+		link = ansiUnderline + webURL + ansiReset
+	}
+	final := g.screen(frame{art: g.Art, quote: g.Quote.Text}, colors, colored)
+	write(fmt.Sprintf("%s\n\n Web View URL: %s\n\n%s", final, link, ansiShowCursor))
+}
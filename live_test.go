@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeartFill(t *testing.T) {
+	heart := heartStyle("Sam", artOptions{})
+	frames := heartFill(heart)
+	rows := strings.Split(strings.Trim(heart, "\n"), "\n")
+	if len(frames) != len(rows) {
+		t.Fatalf("got %d frames, want one per row (%d)", len(frames), len(rows))
+	}
+	prev := 0
+	for i, f := range frames {
+		if got := strings.Count(f.art, "\n"); got != len(rows)+1 {
+			t.Errorf("frame %d has %d lines, want %d so the heart stays in place", i, got, len(rows)+1)
+		}
+		stars := strings.Count(f.art, "*")
+		if stars <= prev {
+			t.Errorf("frame %d shows %d stars, want more than %d", i, stars, prev)
+		}
+		prev = stars
+	}
+	if last := frames[len(frames)-1].art; strings.TrimSpace(last) != strings.TrimSpace(heart) {
+		t.Errorf("last frame is not the full heart:\n%s", last)
+	}
+}
+
+func TestTwinkle(t *testing.T) {
+	art := greetingArt("Sam", fonts[defaultFont])
+	frames := twinkle(art, nameSeed("Sam"))
+	if len(frames) != sparkleFrames {
+		t.Fatalf("got %d frames, want %d", len(frames), sparkleFrames)
+	}
+	unsparkle := strings.NewReplacer("✦", " ", "✧", " ", "+", " ", "·", " ")
+	normalize := func(s string) string {
+		lines := strings.Split(s, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(line, " \t")
+		}
+		return strings.Join(lines, "\n")
+	}
+	changed := false
+	for i, f := range frames {
+		if !strings.ContainsAny(f.art, "✦✧+·") {
+			t.Errorf("frame %d has no sparkles", i)
+		}
+		if f.art != frames[0].art {
+			changed = true
+		}
+		// Sparkles only replace blanks, so removing them restores the art.
+		// '*' is left alone as FIGlet letters may contain it.
+		got := strings.ReplaceAll(unsparkle.Replace(f.art), "*", " ")
+		if normalize(got) != normalize(strings.ReplaceAll(art, "*", " ")) {
+			t.Errorf("frame %d changes the art:\n%s", i, f.art)
+		}
+	}
+	if !changed {
+		t.Error("sparkles do not move between frames")
+	}
+
+	again := twinkle(art, nameSeed("Sam"))
+	for i := range frames {
+		if frames[i].art != again[i].art {
+			t.Fatalf("frame %d differs between runs for the same name", i)
+		}
+	}
+}
+
+func TestTypeOut(t *testing.T) {
+	frames := typeOut("art", "Hi 🇯🇵 you")
+	want := []string{"H▌", "Hi▌", "Hi 🇯🇵▌", "Hi 🇯🇵 y▌", "Hi 🇯🇵 yo▌", "Hi 🇯🇵 you▌", "Hi 🇯🇵 you"}
+	if len(frames) != len(want) {
+		t.Fatalf("got %d frames, want %d", len(frames), len(want))
+	}
+	for i, f := range frames {
+		if f.quote != want[i] {
+			t.Errorf("frame %d quote = %q, want %q", i, f.quote, want[i])
+		}
+	}
+	if last := frames[len(frames)-1]; last.delay != finalHoldDelay {
+		t.Errorf("last frame delay = %s, want %s", last.delay, finalHoldDelay)
+	}
+}
+
+func TestLiveStreamIsCapped(t *testing.T) {
+	setConfig(t, func(c *config) { c.LiveMaxDuration = 300 * time.Millisecond })
+	start := time.Now()
+	rec := doRequest(t, http.MethodGet, "/wish/live?name=Sam", nil)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("stream ran for %s, want it cut off near 300ms", elapsed)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+	if !rec.Flushed {
+		t.Error("frames were not flushed")
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", got)
+	}
+
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, ansiHideCursor+ansiClearScreen) {
+		t.Errorf("stream does not start by hiding the cursor: %q", body[:min(len(body), 20)])
+	}
+	if !strings.HasSuffix(body, ansiShowCursor) {
+		t.Errorf("stream does not end by showing the cursor: %q", body[max(len(body)-20, 0):])
+	}
+	if strings.Count(body, ansiCursorHome) < 2 {
+		t.Error("stream has fewer than two frames")
+	}
+	final := body[strings.LastIndex(body, ansiCursorHome):]
+	if !strings.Contains(final, "\x1b[38;5;") {
+		t.Errorf("final frame is not colored:\n%q", final)
+	}
+	text := sgrPattern.ReplaceAllString(final, "")
+	for _, want := range []string{"ANTASTIC FRIEND", "Web View URL: http://wish.example/wish/web/sam"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("final frame does not contain %q:\n%q", want, text)
+		}
+	}
+}
+
+func TestLiveNoColor(t *testing.T) {
+	setConfig(t, func(c *config) { c.LiveMaxDuration = 100 * time.Millisecond })
+	rec := doRequest(t, http.MethodGet, "/wish/live?name=Sam&no_color", nil)
+	if body := rec.Body.String(); strings.Contains(body, "\x1b[38;") {
+		t.Errorf("no_color stream contains color sequences:\n%q", body)
+	}
+}
+
+func TestLiveDoesNotCountStyles(t *testing.T) {
+	setConfig(t, func(c *config) { c.LiveMaxDuration = 50 * time.Millisecond })
+	count := func() float64 {
+		styleUsage.mu.Lock()
+		defer styleUsage.mu.Unlock()
+		total := 0.0
+		for _, v := range styleUsage.values {
+			total += v
+		}
+		return total
+	}
+	before := count()
+	doRequest(t, http.MethodGet, "/wish/live?name=Sam", nil)
+	doRequest(t, http.MethodGet, "/wish/live?name=Sam&style=cowsay", nil)
+	if got := count() - before; got != 0 {
+		t.Errorf("live requests counted %v styles, want 0", got)
+	}
+}
+
+func TestLiveStopsWhenClientDisconnects(t *testing.T) {
+	setConfig(t, func(c *config) { c.LiveMaxDuration = time.Minute })
+	done := make(chan struct{})
+	h := newHandler(discardLogger())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		h.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/wish/live?name=Sam", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first frame arrives before the stream ends.
+	if _, err := bufio.NewReader(resp.Body).ReadString('$'); err != nil {
+		t.Fatalf("reading the first frame: %v", err)
+	}
+	cancel()
+	resp.Body.Close()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler still streaming after the client disconnected")
+	}
+}
+
+func TestLiveErrors(t *testing.T) {
+	tests := []struct {
+		target string
+		status int
+	}{
+		{"/wish/live", http.StatusBadRequest},
+		{"/wish/live?name=Sam&font=nope", http.StatusBadRequest},
+		{"/wish/live?name=Sam&palette=neon", http.StatusBadRequest},
+		{"/wish/live?name=Sam&style=cowsay", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rec := doRequest(t, http.MethodGet, tt.target, nil); rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.target, rec.Code, tt.status)
+		}
+	}
+
+	setConfig(t, func(c *config) { c.Features.Live = false })
+	if rec := doRequest(t, http.MethodGet, "/wish/live?name=Sam", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("feature off: status = %d, want 404", rec.Code)
+	}
+}
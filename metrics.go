@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The metrics below are exposed at /metrics in the Prometheus text format.
+// Label sets are bounded: routes are mux patterns, and styles and formats
+// are only recorded once they have been resolved against their registries.
+var (
+	httpRequests = newCounterVec("wish_http_requests_total",
+		"HTTP requests by route, method and status.", "route", "method", "status")
+	httpDuration = newHistogramVec("wish_http_request_duration_seconds",
+		"HTTP request latency by route and status.",
+		[]float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}, "route", "status")
+	validationFailures = newCounterVec("wish_validation_failures_total",
+		"Rejected names by reason.", "reason")
+	styleUsage = newCounterVec("wish_style_total",
+		"Greetings rendered by art style.", "style")
+	formatUsage = newCounterVec("wish_format_total",
+		"Greetings rendered by output format.", "format")
+)
+
+var startTime = time.Now()
+
+// labelKey joins label values into a map key.
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// formatLabels renders names and values as {a="x",b="y"}.
+func formatLabels(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", name, values[i])
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", extra[i], extra[i+1])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// counterVec is a counter partitioned by label values.
+type counterVec struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]float64
+	keys   map[string][]string
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+		keys:   make(map[string][]string),
+	}
+}
+
+// inc adds one to the counter with the given label values.
+func (c *counterVec) inc(values ...string) {
+	key := labelKey(values)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.keys[key]; !ok {
+		c.keys[key] = values
+	}
+	c.values[key]++
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.keys) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, c.keys[key]), formatFloat(c.values[key]))
+	}
+}
+
+// histogramVec is a histogram partitioned by label values.
+type histogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*histogram
+}
+
+type histogram struct {
+	values []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*histogram),
+	}
+}
+
+// observe records v in the histogram with the given label values.
+func (h *histogramVec) observe(v float64, values ...string) {
+	key := labelKey(values)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{values: values, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, s.values, "le", formatFloat(upper)), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, s.values, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, s.values), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, s.values), s.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeGauge writes a single unlabelled metric.
+func writeGauge(w io.Writer, name, typ, help string, v float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, help, name, typ, name, formatFloat(v))
+}
+
+// writeRuntimeMetrics writes Go runtime and process statistics.
+func writeRuntimeMetrics(w io.Writer) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	fmt.Fprintf(w, "# HELP go_info Information about the Go environment.\n# TYPE go_info gauge\ngo_info{version=%q} 1\n", runtime.Version())
+	writeGauge(w, "go_goroutines", "gauge", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()))
+	writeGauge(w, "go_threads", "gauge", "Number of OS threads created.", float64(threadCount()))
+	writeGauge(w, "go_memstats_alloc_bytes", "gauge", "Bytes of allocated heap objects.", float64(m.HeapAlloc))
+	writeGauge(w, "go_memstats_sys_bytes", "gauge", "Bytes of memory obtained from the OS.", float64(m.Sys))
+	writeGauge(w, "go_memstats_heap_inuse_bytes", "gauge", "Bytes in in-use heap spans.", float64(m.HeapInuse))
+	writeGauge(w, "go_memstats_heap_objects", "gauge", "Number of allocated heap objects.", float64(m.HeapObjects))
+	writeGauge(w, "go_memstats_mallocs_total", "counter", "Cumulative count of heap objects allocated.", float64(m.Mallocs))
+	writeGauge(w, "go_gc_cycles_total", "counter", "Number of completed GC cycles.", float64(m.NumGC))
+	writeGauge(w, "go_gc_pause_seconds_total", "counter", "Cumulative GC stop-the-world pause time.", float64(m.PauseTotalNs)/1e9)
+	writeGauge(w, "process_start_time_seconds", "gauge", "Start time of the process since the Unix epoch.", float64(startTime.Unix()))
+}
+
+func threadCount() int {
+	n, _ := runtime.ThreadCreateProfile(nil)
+	return n
+}
+
+// metricsHandler serves all metrics in the Prometheus text format.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	bw := bufio.NewWriter(w)
+	httpRequests.write(bw)
+	httpDuration.write(bw)
+	validationFailures.write(bw)
+	styleUsage.write(bw)
+	formatUsage.write(bw)
+	writeRuntimeMetrics(bw)
+	bw.Flush()
+}
+
+// routeLabel names the route that served r by its mux pattern, without
+// the method. Unmatched requests share one label to bound cardinality.
+func routeLabel(r *http.Request) string {
+	_, path, _ := strings.Cut(r.Pattern, " ")
+	switch path {
+	case "", "/":
+		return "unmatched"
+	case "/{$}":
+		return "/"
+	}
+	return path
+}
+
+// withMetrics records the count and latency of every request. It must run
+// inside any middleware that replaces the request, so the pattern set by
+// the mux is visible once the handler returns.
+func withMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		route, code := routeLabel(r), strconv.Itoa(status)
+		httpRequests.inc(route, r.Method, code)
+		httpDuration.observe(time.Since(start).Seconds(), route, code)
+	})
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	doRequest(t, http.MethodGet, "/wish/text/sam?style=heart", nil)
+	doRequest(t, http.MethodGet, "/wish/text?name="+strings.Repeat("a", 37), nil)
+
+	rec := doRequest(t, http.MethodGet, "/metrics", nil)
+	body := rec.Body.String()
+	for _, want := range []string{
+		`wish_http_requests_total{route="/wish/text/{slug}",method="GET",status="200"}`,
+		`wish_http_request_duration_seconds_bucket{route="/wish/text/{slug}",status="200",le="+Inf"}`,
+		`wish_validation_failures_total{reason="length"}`,
+		`wish_style_total{style="heart"}`,
+		`wish_format_total{format="text"}`,
+		"# TYPE go_goroutines gauge",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output is missing %s", want)
+		}
+	}
+}
+
+func TestRouteLabel(t *testing.T) {
+	tests := map[string]string{
+		"GET /{$}":      "/",
+		"/":             "unmatched",
+		"":              "unmatched",
+		"GET /wish/web": "/wish/web",
+	}
+	for pattern, want := range tests {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.Pattern = pattern
+		if got := routeLabel(r); got != want {
+			t.Errorf("routeLabel(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
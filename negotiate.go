@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mediaRange is a single entry of an Accept header.
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// wishRenderer pairs a media type, and the short name accepted by the
+// format query parameter, with the handler that produces it. Renderers
+// behind a feature toggle report it through enabled.
+type wishRenderer struct {
+	mediaType string
+	format    string
+	handler   http.HandlerFunc
+	enabled   func(features) bool
+}
+
+// wishRenderers lists the representations served by /wish. On ties the
+// earlier entry wins, so clients sending */* (curl, httpie) get plain text.
+var wishRenderers = []wishRenderer{
+	{"text/plain", "text", wishTextHandler, nil},
+	{"text/html", "html", wishHTMLHandler, nil},
+	{"application/json", "json", apiWishHandler, func(f features) bool { return f.API }},
+	{"image/svg+xml", "svg", wishSVGHandler, func(f features) bool { return f.SVG }},
+	{"image/png", "png", wishImageHandler, func(f features) bool { return f.Image }},
+}
+
+// activeRenderers returns the renderers whose features are enabled.
+func activeRenderers() []wishRenderer {
+	var active []wishRenderer
+	for _, wr := range wishRenderers {
+		if wr.enabled == nil || wr.enabled(cfg.Features) {
+			active = append(active, wr)
+		}
+	}
+	return active
+}
+
+// parseAccept parses an Accept header into media ranges. Malformed
+// entries are skipped and a missing q parameter defaults to 1.
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mt := strings.ToLower(strings.TrimSpace(params[0]))
+		typ, subtype, ok := strings.Cut(mt, "/")
+		if !ok || typ == "" || subtype == "" {
+			continue
+		}
+
+		mr := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, p := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.ToLower(strings.TrimSpace(key)) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			mr.q = q
+		}
+		ranges = append(ranges, mr)
+	}
+	return ranges
+}
+
+// specificity ranks how closely a media range matches a concrete type:
+// 3 for an exact match, 2 for type/*, 1 for */* and 0 for no match.
+func (mr mediaRange) specificity(mediaType string) int {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	switch {
+	case mr.typ == typ && mr.subtype == subtype:
+		return 3
+	case mr.typ == typ && mr.subtype == "*":
+		return 2
+	case mr.typ == "*" && mr.subtype == "*":
+		return 1
+	}
+	return 0
+}
+
+// negotiate returns the offer preferred by the Accept header, or "" when
+// none is acceptable. An empty header accepts anything.
+func negotiate(header string, offers []string) string {
+	if strings.TrimSpace(header) == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	ranges := parseAccept(header)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, spec := 0.0, 0
+		for _, mr := range ranges {
+			if s := mr.specificity(offer); s > spec {
+				q, spec = mr.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// mediaTypes returns the media types produced by renderers.
+func mediaTypes(renderers []wishRenderer) []string {
+	types := make([]string, 0, len(renderers))
+	for _, wr := range renderers {
+		types = append(types, wr.mediaType)
+	}
+	return types
+}
+
+// wishHandler serves /wish, picking a renderer from the format query
+// parameter when present and from the Accept header otherwise.
+func wishHandler(w http.ResponseWriter, r *http.Request) {
+	renderers := activeRenderers()
+	if format := r.URL.Query().Get("format"); format != "" {
+		for _, wr := range renderers {
+			if strings.EqualFold(wr.format, format) {
+				wr.handler(w, r)
+				return
+			}
+		}
+		formats := make([]string, len(renderers))
+		for i, wr := range renderers {
+			formats[i] = wr.format
+		}
+		http.Error(w, fmt.Sprintf("Unknown format %q. Supported formats: %s", format, strings.Join(formats, ", ")), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept")
+
+	chosen := negotiate(r.Header.Get("Accept"), mediaTypes(renderers))
+	for _, wr := range renderers {
+		if wr.mediaType == chosen {
+			wr.handler(w, r)
+			return
+		}
+	}
+
+	types := mediaTypes(renderers)
+	sort.Strings(types)
+	setTextHeaders(w)
+	w.WriteHeader(http.StatusNotAcceptable)
+	fmt.Fprintf(w, "Not Acceptable. Supported media types:\n%s\n", strings.Join(types, "\n"))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"text/plain", "text/html", "application/json", "image/svg+xml", "image/png"}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "text/plain"},
+		{"*/*", "text/plain"},
+		{"text/html", "text/html"},
+		{"TEXT/HTML", "text/html"},
+		{"text/*", "text/plain"},
+		{"image/*", "image/svg+xml"},
+		{"text/html;q=0.5, application/json", "application/json"},
+		{"text/html, application/json;q=0.9", "text/html"},
+		{"image/png, */*;q=0.1", "image/png"},
+		{"text/*;q=0, application/json;q=0.2", "application/json"},
+		{"text/html;q=bogus, image/png;q=0.3", "image/png"},
+		{"application/pdf", ""},
+		{"garbage", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiate(tt.accept, offers); got != tt.want {
+			t.Errorf("negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestWishHandlerVaryAndNotAcceptable(t *testing.T) {
+	rec := doRequest(t, http.MethodGet, "/wish?name=Sam", http.Header{"Accept": {"text/html"}})
+	if vary := rec.Header().Get("Vary"); vary != "Accept" {
+		t.Errorf("Vary = %q, want Accept", vary)
+	}
+
+	rec = doRequest(t, http.MethodGet, "/wish?name=Sam", http.Header{"Accept": {"application/pdf"}})
+	if rec.Code != http.StatusNotAcceptable {
+		t.Fatalf("status = %d, want 406", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "image/png") {
+		t.Errorf("406 body does not list the supported types: %s", rec.Body)
+	}
+}
+
+func TestWishHandlerSkipsDisabledRenderers(t *testing.T) {
+	setConfig(t, func(c *config) {
+		c.ImageBackend = "https://img.example/card"
+		c.Features.Image = false
+	})
+	rec := doRequest(t, http.MethodGet, "/wish?name=Sam", http.Header{"Accept": {"image/png"}})
+	if rec.Code != http.StatusNotAcceptable {
+		t.Errorf("status = %d, want 406 with the image feature off", rec.Code)
+	}
+}
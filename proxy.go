@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// parseTrustedProxies parses CIDRs or bare IP addresses of the reverse
+// proxies whose forwarding headers may be believed.
+func parseTrustedProxies(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			p, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteAddr returns the IP address of the connection peer.
+func remoteAddr(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// clientIP returns the address of the client that made r. X-Forwarded-For
+// is only consulted when the peer is a trusted proxy, and is read from the
+// right, skipping further trusted proxies, so a client cannot spoof its
+// address by sending the header itself.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer, ok := remoteAddr(r)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !isTrusted(peer, trusted) {
+		return peer.String()
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = addr.Unmap()
+		if !isTrusted(client, trusted) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// origin is the scheme and host a client used to reach the server, after
+// trusted proxy headers and the public host allow-list are applied.
+type origin struct {
+	Scheme string
+	Host   string
+}
+
+type originKey struct{}
+
+// originResolver works out the origin of requests. Forwarded,
+// X-Forwarded-Proto and X-Forwarded-Host are only believed when the peer
+// is a trusted proxy; any client can send them.
+type originResolver struct {
+	trusted []netip.Prefix
+	hosts   []string
+}
+
+func newOriginResolver(c config) originResolver {
+	trusted, _ := parseTrustedProxies(c.TrustedProxies)
+	return originResolver{trusted: trusted, hosts: c.PublicHosts}
+}
+
+func (res originResolver) resolve(r *http.Request) origin {
+	o := origin{Scheme: "http", Host: r.Host}
+	if r.TLS != nil {
+		o.Scheme = "https"
+	}
+
+	if peer, ok := remoteAddr(r); ok && isTrusted(peer, res.trusted) {
+		proto, host := forwardedOrigin(r.Header, res.trusted)
+		if proto == "http" || proto == "https" {
+			o.Scheme = proto
+		}
+		if host != "" && validHost(host) {
+			o.Host = host
+		}
+	}
+
+	if len(res.hosts) > 0 && !hostAllowed(o.Host, res.hosts) {
+		o.Host = res.hosts[0]
+	}
+	return o
+}
+
+// forwardedOrigin returns the protocol and host the outermost trusted
+// proxy received, from the standard Forwarded header or else the
+// X-Forwarded-* ones. Like clientIP it reads the headers from the right,
+// skipping hops added for trusted proxies, as a client can send the
+// headers itself and proxies append to them.
+func forwardedOrigin(h http.Header, trusted []netip.Prefix) (proto, host string) {
+	if elements := listValues(h, "Forwarded"); len(elements) > 0 {
+		i := len(elements) - 1
+		for ; i > 0; i-- {
+			params := forwardedParams(elements[i])
+			if addr, ok := forwardedFor(params["for"]); !ok || !isTrusted(addr, trusted) {
+				break
+			}
+		}
+		params := forwardedParams(elements[i])
+		return strings.ToLower(params["proto"]), params["host"]
+	}
+
+	// Each proxy appends one X-Forwarded-For hop; the proto and host of
+	// the first untrusted hop sit as far from the right of their lists.
+	skip := 0
+	hops := listValues(h, "X-Forwarded-For")
+	for i := len(hops) - 1; i > 0; i-- {
+		addr, err := netip.ParseAddr(hops[i])
+		if err != nil || !isTrusted(addr, trusted) {
+			break
+		}
+		skip++
+	}
+	pick := func(values []string) string {
+		if len(values) == 0 {
+			return ""
+		}
+		return values[max(len(values)-1-skip, 0)]
+	}
+	return strings.ToLower(pick(listValues(h, "X-Forwarded-Proto"))), pick(listValues(h, "X-Forwarded-Host"))
+}
+
+// listValues returns the comma-separated elements of every key header,
+// in order and trimmed.
+func listValues(h http.Header, key string) []string {
+	var values []string
+	for _, header := range h.Values(key) {
+		for _, v := range strings.Split(header, ",") {
+			values = append(values, strings.TrimSpace(v))
+		}
+	}
+	return values
+}
+
+// forwardedParams parses one element of a Forwarded header into its
+// lower-cased parameter names and unquoted values.
+func forwardedParams(element string) map[string]string {
+	params := make(map[string]string)
+	for _, pair := range strings.Split(element, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+		params[strings.ToLower(strings.TrimSpace(key))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return params
+}
+
+// forwardedFor parses the node of a Forwarded for= parameter, an address
+// with an optional port and IPv6 addresses in brackets. Obfuscated and
+// unknown nodes do not parse.
+func forwardedFor(node string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		node = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(node, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// validHost reports whether host is a bare host[:port], with no user info,
+// path or other characters that would change the meaning of a URL.
+func validHost(host string) bool {
+	if host == "" || strings.ContainsAny(host, "/\\@?#% \t") {
+		return false
+	}
+	u, err := url.Parse("http://" + host)
+	return err == nil && u.Host == host
+}
+
+// hostAllowed reports whether host matches an allow-list entry. Entries
+// without a port match the host on any port.
+func hostAllowed(host string, allowed []string) bool {
+	name := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		name = h
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, host) || strings.EqualFold(a, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// withOrigin resolves the request's origin once and stores it in the
+// request context for publicBaseURL and the security headers.
+func withOrigin(res originResolver) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), originKey{}, res.resolve(r))))
+		})
+	}
+}
+
+// requestOrigin returns the origin stored by withOrigin, resolving it with
+// the current configuration when the middleware did not run.
+func requestOrigin(r *http.Request) origin {
+	if o, ok := r.Context().Value(originKey{}).(origin); ok {
+		return o
+	}
+	return newOriginResolver(cfg).resolve(r)
+}
+
+// publicBaseURL returns the base URL of every absolute link the server
+// generates: public_url when configured, else the request's origin.
+func publicBaseURL(r *http.Request) string {
+	if cfg.PublicURL != "" {
+		return strings.TrimRight(cfg.PublicURL, "/")
+	}
+	o := requestOrigin(r)
+	return o.Scheme + "://" + o.Host
+}
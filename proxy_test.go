@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPublicBaseURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		remote string
+		header http.Header
+		modify func(c *config)
+		want   string
+	}{
+		{"plain HTTP", "/", "203.0.113.7:1234", nil, nil, "http://wish.example"},
+		{"TLS", "https://wish.example/", "203.0.113.7:1234", nil, nil, "https://wish.example"},
+		{"untrusted X-Forwarded headers", "/", "203.0.113.7:1234",
+			http.Header{"X-Forwarded-Proto": {"https"}, "X-Forwarded-Host": {"evil.example"}}, nil, "http://wish.example"},
+		{"trusted X-Forwarded headers", "/", "10.0.0.2:1234",
+			http.Header{"X-Forwarded-Proto": {"https"}, "X-Forwarded-Host": {"friends.example"}}, nil, "https://friends.example"},
+		{"spoofed X-Forwarded headers", "/", "10.0.0.2:1234",
+			http.Header{"X-Forwarded-For": {"203.0.113.7"}, "X-Forwarded-Proto": {"http, https"}, "X-Forwarded-Host": {"evil.example", "friends.example"}}, nil, "https://friends.example"},
+		{"X-Forwarded headers behind two proxies", "/", "10.0.0.2:1234",
+			http.Header{"X-Forwarded-For": {"203.0.113.7, 10.0.0.3"}, "X-Forwarded-Proto": {"https, http"}, "X-Forwarded-Host": {"evil.example, friends.example, inner.internal"}}, nil, "https://friends.example"},
+		{"spoofed Forwarded header", "/", "10.0.0.2:1234",
+			http.Header{"Forwarded": {`for=198.51.100.1;proto=http;host=evil.example, for=203.0.113.7;proto=https;host="friends.example:8443"`}, "X-Forwarded-Host": {"other.example"}}, nil, "https://friends.example:8443"},
+		{"Forwarded header behind two proxies", "/", "10.0.0.2:1234",
+			http.Header{"Forwarded": {"host=evil.example", `for=203.0.113.7;proto=https;host=friends.example, for="10.0.0.3:8080";host=inner.internal`}}, nil, "https://friends.example"},
+		{"bogus forwarded values", "/", "10.0.0.2:1234",
+			http.Header{"X-Forwarded-Proto": {"javascript"}, "X-Forwarded-Host": {"evil.example/path"}}, nil, "http://wish.example"},
+		{"host not allowed", "/", "203.0.113.7:1234", nil,
+			func(c *config) { c.PublicHosts = []string{"friends.example", "wish.example.org"} }, "http://friends.example"},
+		{"forwarded host not allowed", "/", "10.0.0.2:1234", http.Header{"X-Forwarded-Host": {"evil.example"}},
+			func(c *config) { c.PublicHosts = []string{"friends.example"} }, "http://friends.example"},
+		{"allowed host on any port", "http://friends.example:8080/", "203.0.113.7:1234", nil,
+			func(c *config) { c.PublicHosts = []string{"wish.example", "FRIENDS.example"} }, "http://friends.example:8080"},
+		{"public_url overrides", "/", "10.0.0.2:1234", http.Header{"X-Forwarded-Host": {"friends.example"}},
+			func(c *config) { c.PublicURL = "https://wish.example.com/" }, "https://wish.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setConfig(t, func(c *config) {
+				c.TrustedProxies = []string{"10.0.0.0/8"}
+				if tt.modify != nil {
+					tt.modify(c)
+				}
+			})
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if !strings.HasPrefix(tt.target, "http") {
+				r.Host = "wish.example"
+			}
+			r.RemoteAddr = tt.remote
+			for key, values := range tt.header {
+				for _, v := range values {
+					r.Header.Add(key, v)
+				}
+			}
+			if got := publicBaseURL(r); got != tt.want {
+				t.Errorf("publicBaseURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpoofedHostDoesNotReachPages(t *testing.T) {
+	setConfig(t, func(c *config) { c.PublicHosts = []string{"wish.example"} })
+	h := newHandler(discardLogger())
+	r := httptest.NewRequest(http.MethodGet, "/wish/web/sam", nil)
+	r.Host = "evil.example"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "evil.example") {
+		t.Error("page links to the spoofed host")
+	}
+	if !strings.Contains(body, `<meta property="og:url" content="http://wish.example/wish/web/sam">`) {
+		t.Error("og:url does not use the allowed host")
+	}
+}
+
+func TestHSTSBehindTrustedProxy(t *testing.T) {
+	setConfig(t, func(c *config) { c.TrustedProxies = []string{"192.0.2.0/24"} })
+	h := newHandler(discardLogger())
+	for _, tt := range []struct {
+		remote string
+		want   bool
+	}{
+		{"192.0.2.10:1234", true},
+		{"203.0.113.7:1234", false},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		r.RemoteAddr = tt.remote
+		r.Header.Set("X-Forwarded-Proto", "https")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if got := rec.Header().Get("Strict-Transport-Security") != ""; got != tt.want {
+			t.Errorf("peer %s: HSTS sent = %v, want %v", tt.remote, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed quotes.yaml
+var defaultQuotes []byte
+
+var errUnknownQuote = errors.New("unknown quote")
+
+// quote is a single entry of the quote corpus.
+type quote struct {
+	ID   string `json:"id" yaml:"id"`
+	Text string `json:"text" yaml:"text"`
+}
+
+type quoteFile struct {
+	Quotes []quote `json:"quotes" yaml:"quotes"`
+}
+
+// quotes is the loaded corpus: the embedded defaults followed by any
+// quotes from an external file.
+var quotes = mustLoadQuotes("")
+
+// parseQuotes decodes a quote file, choosing JSON or YAML by extension.
+func parseQuotes(name string, data []byte) ([]quote, error) {
+	var qf quoteFile
+	var err error
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		err = json.Unmarshal(data, &qf)
+	default:
+		err = yaml.Unmarshal(data, &qf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return qf.Quotes, nil
+}
+
+// loadQuotes returns the embedded quotes extended by the quotes in file
+// (when non-empty). A quote in file replaces an embedded one with the same id.
+func loadQuotes(file string) ([]quote, error) {
+	loaded, err := parseQuotes("quotes.yaml", defaultQuotes)
+	if err != nil {
+		return nil, err
+	}
+
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		extra, err := parseQuotes(file, data)
+		if err != nil {
+			return nil, err
+		}
+		loaded = mergeQuotes(loaded, extra)
+	}
+
+	seen := make(map[string]bool, len(loaded))
+	for i, q := range loaded {
+		q.ID = strings.TrimSpace(q.ID)
+		q.Text = strings.TrimSpace(q.Text)
+		if q.ID == "" || q.Text == "" {
+			return nil, fmt.Errorf("quote %d: id and text are required", i+1)
+		}
+		if seen[q.ID] {
+			return nil, fmt.Errorf("quote %q: duplicate id", q.ID)
+		}
+		seen[q.ID] = true
+		loaded[i] = q
+	}
+	if len(loaded) == 0 {
+		return nil, errors.New("no quotes loaded")
+	}
+	return loaded, nil
+}
+
+func mergeQuotes(base, extra []quote) []quote {
+	index := make(map[string]int, len(base))
+	for i, q := range base {
+		index[q.ID] = i
+	}
+	for _, q := range extra {
+		if i, ok := index[q.ID]; ok {
+			base[i] = q
+			continue
+		}
+		index[q.ID] = len(base)
+		base = append(base, q)
+	}
+	return base
+}
+
+func mustLoadQuotes(file string) []quote {
+	q, err := loadQuotes(file)
+	if err != nil {
+		log.Fatalf("Failed to load quotes: %v", err)
+	}
+	return q
+}
+
+// quoteFor returns the quote shown alongside the greeting for name. The
+// choice hashes the slug its share link carries, so "John Doe" and
+// "john-doe", or "José" and "jose" with transliterated slugs, always get
+// the same quote.
+func quoteFor(name string) quote {
+	key := slugFor(cleanName(name), cfg.TransliterateSlugs)
+	if key == "" {
+		key = strings.ToLower(strings.TrimSpace(name))
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return quotes[h.Sum32()%uint32(len(quotes))]
+}
+
+// lookupQuote returns the quote with the given id.
+func lookupQuote(id string) (quote, error) {
+	for _, q := range quotes {
+		if q.ID == id {
+			return q, nil
+		}
+	}
+	return quote{}, fmt.Errorf("%w %q", errUnknownQuote, id)
+}
+
+// quoteFromRequest returns the quote pinned by the quote query parameter,
+// falling back to the hashed choice for name.
+func quoteFromRequest(r *http.Request, name string) (quote, error) {
+	if id := r.URL.Query().Get("quote"); id != "" {
+		return lookupQuote(id)
+	}
+	return quoteFor(name), nil
+}
+
+// indentLines prefixes every line of s with prefix.
+func indentLines(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = prefix + l
+	}
+	return strings.Join(lines, "\n")
+}
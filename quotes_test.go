@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQuoteForIsStableAcrossSlugs(t *testing.T) {
+	for _, pair := range [][2]string{{"John Doe", "john-doe"}, {"Mary-Jane", "mary jane"}, {"José", "josé"}} {
+		if a, b := quoteFor(pair[0]), quoteFor(pair[1]); a.ID != b.ID {
+			t.Errorf("quoteFor(%q) = %s but quoteFor(%q) = %s", pair[0], a.ID, pair[1], b.ID)
+		}
+	}
+}
+
+func TestQuoteMatchesTransliteratedShareLink(t *testing.T) {
+	setConfig(t, func(c *config) { c.TransliterateSlugs = true })
+	for _, name := range []string{"José", "Björn", "Дмитрий"} {
+		var wish apiWish
+		rec := doRequest(t, http.MethodGet, "/api/v1/wish?name="+url.QueryEscape(name), nil)
+		if err := json.Unmarshal(rec.Body.Bytes(), &wish); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var shared apiWish
+		rec = doRequest(t, http.MethodGet, "/api/v1/wish?name="+url.QueryEscape(wish.Slug), nil)
+		if err := json.Unmarshal(rec.Body.Bytes(), &shared); err != nil {
+			t.Fatalf("%s: %v", wish.Slug, err)
+		}
+		if wish.QuoteID != shared.QuoteID {
+			t.Errorf("%s gets quote %s but its share link %s gets %s", name, wish.QuoteID, wish.Slug, shared.QuoteID)
+		}
+	}
+}
+
+func TestLoadQuotesMergesByID(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "quotes.json")
+	data := `{"quotes": [{"id": "stars", "text": "Replaced"}, {"id": "extra", "text": "Added"}]}`
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadQuotes(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := make(map[string]string, len(loaded))
+	for _, q := range loaded {
+		byID[q.ID] = q.Text
+	}
+	if byID["stars"] != "Replaced" {
+		t.Errorf("quote stars = %q, want the file's text", byID["stars"])
+	}
+	if byID["extra"] != "Added" {
+		t.Errorf("quote extra = %q, want it added", byID["extra"])
+	}
+	if len(loaded) != len(quotes)+1 {
+		t.Errorf("loaded %d quotes, want %d", len(loaded), len(quotes)+1)
+	}
+}
+
+func TestLoadQuotesRejectsInvalid(t *testing.T) {
+	tests := map[string]string{
+		"missing-id.yaml": "quotes:\n  - text: No id\n",
+		"empty.yaml":      "quotes:\n  - id: empty\n    text: \"  \"\n",
+		"broken.json":     "{",
+	}
+	for name, data := range tests {
+		file := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadQuotes(file); err == nil {
+			t.Errorf("loadQuotes(%s) succeeded, want an error", name)
+		}
+	}
+}
+
+func TestIndentLines(t *testing.T) {
+	if got := indentLines("a\nb", "  "); got != "  a\n  b" {
+		t.Errorf("indentLines = %q", got)
+	}
+}
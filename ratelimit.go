@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimit configures a token bucket: clients get burst requests up
+// front, refilled at rate requests per second.
+type rateLimit struct {
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// sweepInterval is how often idle clients are evicted from a limiter.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter is an in-memory token bucket rate limiter keyed by client.
+type limiter struct {
+	limit rateLimit
+	now   func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newLimiter(limit rateLimit) *limiter {
+	return &limiter{
+		limit:   limit,
+		now:     time.Now,
+		clients: make(map[string]*bucket),
+	}
+}
+
+// refillTime is how long an empty bucket takes to fill up again. A client
+// idle for longer is indistinguishable from a new one and can be evicted.
+func (l *limiter) refillTime() time.Duration {
+	return time.Duration(float64(l.limit.Burst) / l.limit.Rate * float64(time.Second))
+}
+
+// allow takes a token for key. It returns whether the request may proceed,
+// the tokens left, and how long until the next token (when denied) or
+// until the bucket is full again (when allowed).
+func (l *limiter) allow(key string) (bool, int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.clients[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit.Burst), last: now}
+		l.clients[key] = b
+	}
+
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(float64(l.limit.Burst), b.tokens+elapsed*l.limit.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.limit.Rate * float64(time.Second))
+		return false, 0, wait
+	}
+
+	b.tokens--
+	full := time.Duration((float64(l.limit.Burst) - b.tokens) / l.limit.Rate * float64(time.Second))
+	return true, int(b.tokens), full
+}
+
+// sweep evicts clients whose buckets have refilled completely.
+func (l *limiter) sweep(now time.Time) {
+	idle := l.refillTime()
+	for key, b := range l.clients {
+		if now.Sub(b.last) >= idle {
+			delete(l.clients, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// ceilSeconds rounds d up to whole seconds for header values.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// withRateLimit limits requests per client IP with l, answering 429 with
+// Retry-After once a client's bucket is empty.
+func withRateLimit(l *limiter, trusted []netip.Prefix) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, remaining, wait := l.allow(clientIP(r, trusted))
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(l.limit.Burst))
+			h.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+			h.Set("RateLimit-Reset", ceilSeconds(wait))
+
+			if !ok {
+				h.Set("Retry-After", ceilSeconds(wait))
+				if strings.HasPrefix(r.URL.Path, "/api/") {
+					writeAPIError(w, http.StatusTooManyRequests, "rate_limited", "Too many requests, retry later")
+					return
+				}
+				setTextHeaders(w)
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte("Too many requests, retry later\n"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newLimiter(rateLimit{Rate: 1, Burst: 2})
+	l.now = func() time.Time { return now }
+
+	for i, want := range []bool{true, true, false} {
+		if ok, _, _ := l.allow("a"); ok != want {
+			t.Fatalf("request %d allowed = %v, want %v", i+1, ok, want)
+		}
+	}
+	if ok, _, _ := l.allow("b"); !ok {
+		t.Error("a second client shares the first client's bucket")
+	}
+
+	_, _, wait := l.allow("a")
+	if wait != time.Second {
+		t.Errorf("retry after = %s, want 1s", wait)
+	}
+	now = now.Add(time.Second)
+	if ok, remaining, _ := l.allow("a"); !ok || remaining != 0 {
+		t.Errorf("after refill: allowed = %v, remaining = %d, want true, 0", ok, remaining)
+	}
+}
+
+func TestLimiterEvictsIdleClients(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newLimiter(rateLimit{Rate: 1, Burst: 2})
+	l.now = func() time.Time { return now }
+
+	l.allow("a")
+	now = now.Add(sweepInterval)
+	l.allow("b")
+	if _, ok := l.clients["a"]; ok {
+		t.Error("idle client was not evicted")
+	}
+	if _, ok := l.clients["b"]; !ok {
+		t.Error("active client was evicted")
+	}
+}
+
+func TestRateLimitedRoute(t *testing.T) {
+	setConfig(t, func(c *config) {
+		c.RateLimits = rateLimits{"/wish/text": {Rate: 0.001, Burst: 2}, "/api/v1/wish": {Rate: 0.001, Burst: 1}}
+	})
+	h := newHandler(discardLogger())
+
+	for i := 0; i < 2; i++ {
+		if rec := doRequestWith(t, h, http.MethodGet, "/wish/text/sam", nil); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, rec.Code)
+		}
+	}
+	rec := doRequestWith(t, h, http.MethodGet, "/wish/text/sam", nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rec.Code)
+	}
+	for _, header := range []string{"Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"} {
+		if rec.Header().Get(header) == "" {
+			t.Errorf("429 response is missing %s", header)
+		}
+	}
+
+	doRequestWith(t, h, http.MethodGet, "/api/v1/wish?name=Sam", nil)
+	rec = doRequestWith(t, h, http.MethodGet, "/api/v1/wish?name=Sam", nil)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("API 429: status = %d, Content-Type = %q, want a JSON error", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		remote string
+		xff    string
+		want   string
+	}{
+		{"203.0.113.7:1234", "", "203.0.113.7"},
+		{"203.0.113.7:1234", "198.51.100.1", "203.0.113.7"},
+		{"10.1.2.3:1234", "", "10.1.2.3"},
+		{"10.1.2.3:1234", "198.51.100.1", "198.51.100.1"},
+		{"10.1.2.3:1234", "6.6.6.6, 198.51.100.1", "198.51.100.1"},
+		{"10.1.2.3:1234", "198.51.100.1, 192.0.2.1, 10.9.9.9", "198.51.100.1"},
+		{"10.1.2.3:1234", "10.2.2.2", "10.2.2.2"},
+		{"10.1.2.3:1234", "not-an-ip", "10.1.2.3"},
+		{"[::ffff:10.1.2.3]:1234", "198.51.100.1", "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.xff != "" {
+			r.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		if got := clientIP(r, trusted); got != tt.want {
+			t.Errorf("clientIP(remote %s, XFF %q) = %s, want %s", tt.remote, tt.xff, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// newHandler returns the routes wrapped in the middleware stack: request
+// ids outermost, then origin resolution, security headers, access logging,
+// metrics and panic recovery. Middleware replacing the request with
+// r.WithContext must stay outside withMetrics, which reads the pattern the
+// mux sets on it.
+func newHandler(logger *slog.Logger) http.Handler {
+	return chain(newMux(),
+		withRequestID,
+		withOrigin(newOriginResolver(cfg)),
+		withSecurityHeaders,
+		withAccessLog(logger),
+		withMetrics,
+		withRecovery(logger),
+	)
+}
+
+// newAdminHandler returns the routes served on the admin listener, or nil
+// when no admin listener is configured.
+func newAdminHandler() http.Handler {
+	if cfg.AdminListen == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	handleProbes(mux)
+	if cfg.Features.Metrics {
+		mux.HandleFunc("GET /metrics", metricsHandler)
+	}
+	return mux
+}
+
+// newMux registers the routes enabled by cfg. Every route except the CSP
+// report endpoint only answers GET and HEAD; the mux replies 405 with an
+// Allow header to other methods.
+func newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	limited := rateLimited()
+
+	mux.HandleFunc("GET /{$}", homeHandler)
+	mux.Handle("GET /wish", limited("/wish", wishHandler))
+	handleSlugRoutes(mux, "/wish/web", limited("/wish/web", canonicalSlug("/wish/web", wishHTMLHandler)))
+	handleSlugRoutes(mux, "/wish/text", limited("/wish/text", canonicalSlug("/wish/text", wishTextHandler)))
+	if cfg.Features.Image {
+		mux.Handle("GET /wish/image.png", limited("/wish/image.png", wishImageHandler))
+	}
+	if cfg.Features.SVG {
+		mux.Handle("GET /wish/svg", limited("/wish/svg", wishSVGHandler))
+	}
+	if cfg.Features.Live {
+		mux.Handle("GET /wish/live", limited("/wish/live", wishLiveHandler))
+	}
+	if cfg.Features.API {
+		mux.Handle("GET /api/v1/wish", limited("/api/v1/wish", apiWishHandler))
+		mux.Handle("GET /api/v1/slug", limited("/api/v1/slug", apiSlugHandler))
+		mux.HandleFunc("GET /styles", stylesHandler)
+	}
+	mux.HandleFunc("GET /static/{path...}", staticHandler)
+	mux.HandleFunc("GET /favicon.ico", faviconHandler("favicon.ico"))
+	mux.HandleFunc("GET /favicon-196.png", faviconHandler("favicon-196.png"))
+	mux.Handle("POST "+cspReportPath, limited(cspReportPath, cspReportHandler))
+	handleProbes(mux)
+	if cfg.Features.Metrics && cfg.AdminListen == "" {
+		mux.HandleFunc("GET /metrics", metricsHandler)
+	}
+	mux.HandleFunc("GET /404", notFoundHandler)
+	mux.HandleFunc("GET /500", internalServerErrorHandler)
+	mux.Handle("/", fallbackHandler(mux))
+
+	return mux
+}
+
+// handleProbes registers the health, readiness and version endpoints. They
+// are served on both listeners so probes work whichever port they target.
+func handleProbes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", healthzHandler)
+	mux.HandleFunc("GET /readyz", readyzHandler)
+	mux.HandleFunc("GET /version", versionHandler)
+}
+
+// handleSlugRoutes registers h for a wish route both with the name in the
+// query (/wish/web?name=John) and as a pretty path (/wish/web/john).
+func handleSlugRoutes(mux *http.ServeMux, base string, h http.Handler) {
+	mux.Handle("GET "+base, h)
+	mux.Handle("GET "+base+"/{slug}", h)
+}
+
+// displayParam carries the name as it was typed across the redirect to
+// the canonical slug, so "Tom & Jerry" is still greeted as such. It is not
+// part of the canonical link, which only names the slug.
+const displayParam = "display"
+
+// canonicalSlug redirects every spelling of a name to the route's pretty
+// path with the canonical slug, e.g. /wish/web?name=John+Doe and
+// /wish/web/John-Doe to /wish/web/john-doe?display=John+Doe, keeping other
+// parameters. Requests already in canonical form reach next with the
+// display name, when it belongs to the slug, or else the slug as name.
+// Names that fail validation reach next as the name parameter, so next
+// reports the error and counts it once.
+func canonicalSlug(base string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		name, fromPath := r.PathValue("slug"), true
+		if query.Has("name") {
+			name, fromPath = query.Get("name"), false
+		}
+
+		validName, err := checkName(name)
+		slug := ""
+		if err == nil {
+			slug = slugFor(validName, cfg.TransliterateSlugs)
+		}
+		if slug == "" {
+			query.Set("name", name)
+			next(w, withQuery(r, query))
+			return
+		}
+
+		if !fromPath || r.PathValue("slug") != slug {
+			query.Del("name")
+			if !fromPath || !query.Has(displayParam) {
+				query.Del(displayParam)
+				if cleanName(validName) != cleanName(slug) {
+					query.Set(displayParam, validName)
+				}
+			}
+			target := base + "/" + url.PathEscape(slug)
+			if len(query) > 0 {
+				target += "?" + query.Encode()
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+
+		display := slug
+		if d, err := checkName(query.Get(displayParam)); err == nil && slugFor(d, cfg.TransliterateSlugs) == slug {
+			display = d
+		}
+		query.Del(displayParam)
+		query.Set("name", display)
+		next(w, withQuery(r, query))
+	}
+}
+
+// withQuery returns a copy of r with its query string replaced.
+func withQuery(r *http.Request, query url.Values) *http.Request {
+	r2 := r.Clone(r.Context())
+	r2.URL.RawQuery = query.Encode()
+	return r2
+}
+
+// rateLimited returns a function wrapping a route's handler with its
+// configured rate limit. Each route gets its own limiter; routes without a
+// limit, or all routes when the feature is off, are left unwrapped.
+func rateLimited() func(path string, h http.HandlerFunc) http.Handler {
+	trusted, _ := parseTrustedProxies(cfg.TrustedProxies)
+	return func(path string, h http.HandlerFunc) http.Handler {
+		limit, ok := cfg.RateLimits[path]
+		if !cfg.Features.RateLimit || !ok {
+			return h
+		}
+		return withRateLimit(newLimiter(limit), trusted)(h)
+	}
+}
+
+// routeMethods are the methods fallbackHandler probes the mux with to
+// tell an unknown path from a known route used with the wrong method.
+var routeMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// fallbackHandler answers requests no route matched, whatever their
+// method: a path with trailing slashes is redirected to its canonical
+// route when one exists, a known path used with another method gets 405
+// with an Allow header, and anything else gets the 404 page.
+func fallbackHandler(mux *http.ServeMux) http.HandlerFunc {
+	// routed reports whether a request for path with method reaches a
+	// route other than this fallback.
+	routed := func(r *http.Request, method, path string) bool {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		probe.URL.Path, probe.URL.RawPath = path, ""
+		_, pattern := mux.Handler(probe)
+		return pattern != "" && pattern != "/"
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		trimmed := strings.TrimRight(r.URL.Path, "/")
+		if trimmed != r.URL.Path && trimmed != "" && routed(r, r.Method, trimmed) {
+			target := *r.URL
+			target.Path, target.RawPath = trimmed, ""
+			http.Redirect(w, r, target.RequestURI(), http.StatusMovedPermanently)
+			return
+		}
+
+		var allow []string
+		for _, method := range routeMethods {
+			if routed(r, method, r.URL.Path) {
+				allow = append(allow, method)
+			}
+		}
+		if len(allow) > 0 {
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		notFoundHandler(w, r)
+	}
+}
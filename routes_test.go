@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// doRequest sends one request through the full handler stack.
+func doRequest(t *testing.T, method, target string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	return doRequestWith(t, newHandler(discardLogger()), method, target, header)
+}
+
+func doRequestWith(t *testing.T, h http.Handler, method, target string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, target, nil)
+	r.Host = "wish.example"
+	for key, values := range header {
+		for _, v := range values {
+			r.Header.Add(key, v)
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestRoutes(t *testing.T) {
+	tests := []struct {
+		method      string
+		target      string
+		header      http.Header
+		status      int
+		contentType string
+		location    string
+	}{
+		{"GET", "/", nil, 200, "text/html", ""},
+		{"HEAD", "/", nil, 200, "text/html", ""},
+		{"GET", "/wish?name=Sam", nil, 200, "text/plain", ""},
+		{"GET", "/wish?name=Sam", http.Header{"Accept": {"text/html"}}, 200, "text/html", ""},
+		{"GET", "/wish?name=Sam", http.Header{"Accept": {"application/json"}}, 200, "application/json", ""},
+		{"GET", "/wish?name=Sam", http.Header{"Accept": {"image/svg+xml"}}, 200, "image/svg+xml", ""},
+		{"GET", "/wish?name=Sam", http.Header{"Accept": {"image/png"}}, 200, "image/png", ""},
+		{"GET", "/wish?name=Sam", http.Header{"Accept": {"application/pdf"}}, 406, "text/plain", ""},
+		{"GET", "/wish?name=Sam&format=json", nil, 200, "application/json", ""},
+		{"GET", "/wish?name=Sam&format=pdf", nil, 400, "text/plain", ""},
+		{"GET", "/wish/web/sam", nil, 200, "text/html", ""},
+		{"GET", "/wish/web?name=Sam", nil, 301, "", "/wish/web/sam?display=Sam"},
+		{"GET", "/wish/web?name=John+Doe&style=heart", nil, 301, "", "/wish/web/john-doe?display=John+Doe&style=heart"},
+		{"GET", "/wish/web/John-Doe", nil, 301, "", "/wish/web/john-doe?display=John-Doe"},
+		{"GET", "/wish/web/john%20doe", nil, 301, "", "/wish/web/john-doe"},
+		{"GET", "/wish/web/jos%C3%A9", nil, 200, "text/html", ""},
+		{"GET", "/wish/web/Jos%C3%A9", nil, 301, "", "/wish/web/jos%C3%A9?display=Jos%C3%A9"},
+		{"GET", "/wish/web/john-doe/", nil, 301, "", "/wish/web/john-doe"},
+		{"GET", "/wish/web", nil, 303, "", "/"},
+		{"GET", "/wish/web?name=" + strings.Repeat("a", 37), nil, 400, "text/plain", ""},
+		{"GET", "/wish/web/sam?style=nope", nil, 400, "text/plain", ""},
+		{"GET", "/wish/text/sam", nil, 200, "text/plain", ""},
+		{"GET", "/wish/text?name=Sam", nil, 301, "", "/wish/text/sam?display=Sam"},
+		{"GET", "/wish/text/Sam?style=card", nil, 301, "", "/wish/text/sam?display=Sam&style=card"},
+		{"GET", "/wish/text", nil, 400, "text/plain", ""},
+		{"GET", "/wish/text?name=%00", nil, 400, "text/plain", ""},
+		{"GET", "/wish/text/sam?font=nope", nil, 400, "text/plain", ""},
+		{"GET", "/wish/text/sam?quote=nope", nil, 400, "text/plain", ""},
+		{"GET", "/wish/text/sam%00", nil, 400, "text/plain", ""},
+		{"GET", "/wish/image.png?name=Sam", nil, 200, "image/png", ""},
+		{"GET", "/wish/image.png", nil, 400, "text/plain", ""},
+		{"GET", "/wish/svg?name=Sam", nil, 200, "image/svg+xml", ""},
+		{"GET", "/wish/svg?name=Sam&theme=nope", nil, 400, "text/plain", ""},
+		{"GET", "/wish/svg", nil, 400, "text/plain", ""},
+		{"GET", "/api/v1/wish?name=Sam", nil, 200, "application/json", ""},
+		{"GET", "/api/v1/wish", nil, 400, "application/json", ""},
+		{"GET", "/api/v1/wish?name=Sam&style=nope", nil, 400, "application/json", ""},
+		{"GET", "/styles", nil, 200, "application/json", ""},
+		{"GET", "/healthz", nil, 200, "text/plain", ""},
+		{"GET", "/readyz", nil, 200, "application/json", ""},
+		{"GET", "/version", nil, 200, "application/json", ""},
+		{"GET", "/metrics", nil, 200, "text/plain", ""},
+		{"GET", "/favicon.ico", nil, 200, "image/x-icon", ""},
+		{"HEAD", "/favicon-196.png", nil, 200, "image/png", ""},
+		{"GET", "/static/css/fonts.css", nil, 200, "text/css", ""},
+		{"GET", "/static/nope.css", nil, 404, "text/plain", ""},
+		{"GET", "/404", nil, 404, "text/html", ""},
+		{"GET", "/500", nil, 500, "text/html", ""},
+		{"GET", "/nope", nil, 404, "text/html", ""},
+		{"GET", "/wish/web/?name=Sam", nil, 301, "", "/wish/web?name=Sam"},
+		{"POST", "/wish/text?name=Sam", nil, 405, "text/plain", ""},
+		{"POST", "/csp-report", nil, 400, "text/plain", ""},
+		{"DELETE", "/", nil, 405, "text/plain", ""},
+		{"POST", "/nope", nil, 404, "text/html", ""},
+		{"GET", "/csp-report", nil, 405, "text/plain", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			rec := doRequest(t, tt.method, tt.target, tt.header)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.status, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %q", ct, tt.contentType)
+			}
+			if loc := rec.Header().Get("Location"); loc != tt.location {
+				t.Errorf("Location = %q, want %q", loc, tt.location)
+			}
+			if rec.Header().Get(requestIDHeader) == "" {
+				t.Errorf("missing %s header", requestIDHeader)
+			}
+		})
+	}
+}
+
+// followRedirect requests target and, when it answers with a redirect,
+// the Location it points to.
+func followRedirect(t *testing.T, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := doRequest(t, http.MethodGet, target, nil)
+	if rec.Code != http.StatusMovedPermanently {
+		return rec
+	}
+	return doRequest(t, http.MethodGet, rec.Header().Get("Location"), nil)
+}
+
+func TestCanonicalSlugKeepsDisplayName(t *testing.T) {
+	rec := followRedirect(t, "/wish/web?name=Tom+%26+Jerry")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "<title>Tom &amp; Jerry : Happy Friendship Wishes</title>") {
+		t.Errorf("page does not greet the name as typed:\n%s", body)
+	}
+	if !strings.Contains(body, `<link rel="canonical" href="http://wish.example/wish/web/tom-jerry">`) {
+		t.Error("canonical link is not the bare slug")
+	}
+
+	text := followRedirect(t, "/wish/text?name=Tom+%26+Jerry").Body.String()
+	if !strings.Contains(text, "wishes@Tom & Jerry:~") {
+		t.Errorf("text does not greet the name as typed:\n%s", text)
+	}
+
+	// A display name belonging to another slug is ignored.
+	spoofed := doRequest(t, http.MethodGet, "/wish/text/sam?display=Someone+Else", nil).Body.String()
+	if !strings.Contains(spoofed, "wishes@sam:~") {
+		t.Errorf("display name of another slug was used:\n%s", spoofed)
+	}
+}
+
+func TestSlugFormKeepsTypedName(t *testing.T) {
+	const navigate = `target += '?display=' + encodeURIComponent(name);`
+	for _, page := range []string{"/", "/wish/web/sam"} {
+		if body := doRequest(t, http.MethodGet, page, nil).Body.String(); !strings.Contains(body, navigate) {
+			t.Errorf("%s: form script does not pass the typed name along", page)
+		}
+	}
+
+	// The URL the script navigates to for Tom & Jerry, with the name
+	// escaped like encodeURIComponent, greets the name as typed.
+	name := "Tom & Jerry"
+	var slug apiSlug
+	json.Unmarshal(doRequest(t, http.MethodGet, "/api/v1/slug?name="+url.QueryEscape(name), nil).Body.Bytes(), &slug)
+	target := "/wish/web/" + url.PathEscape(slug.Slug) + "?display=" + strings.ReplaceAll(url.QueryEscape(name), "+", "%20")
+	rec := doRequest(t, http.MethodGet, target, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: status = %d, want 200", target, rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "<title>Tom &amp; Jerry : Happy Friendship Wishes</title>") {
+		t.Errorf("%s does not greet the name as typed", target)
+	}
+}
+
+func TestPrettyPathErrors(t *testing.T) {
+	tests := []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"/wish/text/" + strings.Repeat("a", 37), 400, errNameLength.Error()},
+		{"/wish/web/" + strings.Repeat("a", 37), 400, errNameLength.Error()},
+		{"/wish/text/%E2%80%AEabc", 400, errNameBidi.Error()},
+		{"/wish/web/%E2%80%AEabc", 400, errNameBidi.Error()},
+		{"/wish/text?name=" + strings.Repeat("a", 37), 400, errNameLength.Error()},
+	}
+	for _, tt := range tests {
+		rec := doRequest(t, http.MethodGet, tt.target, nil)
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.body) {
+			t.Errorf("%s: got %d %q, want %d %q", tt.target, rec.Code, rec.Body, tt.status, tt.body)
+		}
+	}
+}
+
+func TestRejectedNameCountedOnce(t *testing.T) {
+	count := func() float64 {
+		validationFailures.mu.Lock()
+		defer validationFailures.mu.Unlock()
+		return validationFailures.values[labelKey([]string{"bidi_override"})]
+	}
+	for _, target := range []string{"/wish/text?name=%E2%80%AEabc", "/wish/web/%E2%80%AEabc"} {
+		before := count()
+		doRequest(t, http.MethodGet, target, nil)
+		if got := count() - before; got != 1 {
+			t.Errorf("%s counted %v validation failures, want 1", target, got)
+		}
+	}
+}
+
+func TestMethodNotAllowedListsAllow(t *testing.T) {
+	rec := doRequest(t, http.MethodPost, "/wish/text?name=Sam", nil)
+	if allow := rec.Header().Get("Allow"); !strings.Contains(allow, "GET") {
+		t.Errorf("Allow = %q, want GET", allow)
+	}
+}
+
+func TestDisabledFeatures(t *testing.T) {
+	setConfig(t, func(c *config) {
+		c.ImageBackend = "https://img.example/card"
+		c.Features = features{}
+	})
+	for _, target := range []string{"/wish/image.png?name=Sam", "/wish/svg?name=Sam", "/api/v1/wish?name=Sam", "/styles", "/metrics"} {
+		if rec := doRequest(t, http.MethodGet, target, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s with the feature off: status = %d, want 404", target, rec.Code)
+		}
+	}
+}
+
+func TestAdminListenerServesMetrics(t *testing.T) {
+	setConfig(t, func(c *config) { c.AdminListen = "127.0.0.1:9090" })
+	if rec := doRequest(t, http.MethodGet, "/metrics", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("/metrics on the public listener: status = %d, want 404", rec.Code)
+	}
+	admin := newAdminHandler()
+	for _, target := range []string{"/metrics", "/healthz", "/readyz", "/version"} {
+		if rec := doRequestWith(t, admin, http.MethodGet, target, nil); rec.Code != http.StatusOK {
+			t.Errorf("GET %s on the admin listener: status = %d, want 200", target, rec.Code)
+		}
+	}
+}
+
+func TestRecoveryServes500(t *testing.T) {
+	h := chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), withRequestID, withRecovery(discardLogger()))
+	rec := doRequestWith(t, h, http.MethodGet, "/", nil)
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+}
+
+func TestRequestIDIsReused(t *testing.T) {
+	rec := doRequest(t, http.MethodGet, "/healthz", http.Header{requestIDHeader: {"abc-123"}})
+	if got := rec.Header().Get(requestIDHeader); got != "abc-123" {
+		t.Errorf("%s = %q, want the incoming id", requestIDHeader, got)
+	}
+	rec = doRequest(t, http.MethodGet, "/healthz", http.Header{requestIDHeader: {"bad id\n"}})
+	if got := rec.Header().Get(requestIDHeader); got == "bad id\n" || got == "" {
+		t.Errorf("%s = %q, want a fresh id", requestIDHeader, got)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// defaultCSP only allows resources from the server itself, plus inline
+// scripts and styles carrying the request's nonce. Images may come from
+// any HTTPS host so an external image_backend keeps working.
+const defaultCSP = "default-src 'self'; " +
+	"script-src 'self' 'nonce-{nonce}'; " +
+	"style-src 'self' 'nonce-{nonce}'; " +
+	"img-src 'self' data: https:; " +
+	"font-src 'self'; " +
+	"connect-src 'self'; " +
+	"object-src 'none'; " +
+	"base-uri 'none'; " +
+	"form-action 'self'; " +
+	"frame-ancestors 'none'; " +
+	"report-uri /csp-report; " +
+	"report-to csp-endpoint"
+
+// cspReportPath receives violation reports; the default policy names it in
+// report-uri and, through the Reporting-Endpoints header, report-to.
+const cspReportPath = "/csp-report"
+
+// maxCSPReportBytes bounds the size of a violation report body.
+const maxCSPReportBytes = 64 << 10
+
+type cspNonceKey struct{}
+
+// cspNonce returns the nonce stored in ctx by withSecurityHeaders, or ""
+// outside the middleware.
+func cspNonce(ctx context.Context) string {
+	nonce, _ := ctx.Value(cspNonceKey{}).(string)
+	return nonce
+}
+
+// newCSPNonce returns 128 random bits in URL-safe base64, which templates
+// output without escaping. Tests replace it to get stable output.
+var newCSPNonce = func() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// withSecurityHeaders sets the configured header policy on every response
+// and stores a fresh CSP nonce in the request context for the templates.
+func withSecurityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy := cfg.SecurityHeaders
+		nonce := newCSPNonce()
+
+		h := w.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		if policy.ContentSecurityPolicy != "" {
+			name := "Content-Security-Policy"
+			if policy.CSPReportOnly {
+				name = "Content-Security-Policy-Report-Only"
+			}
+			h.Set(name, strings.ReplaceAll(policy.ContentSecurityPolicy, "{nonce}", nonce))
+			if strings.Contains(policy.ContentSecurityPolicy, "report-to csp-endpoint") {
+				h.Set("Reporting-Endpoints", `csp-endpoint="`+cspReportPath+`"`)
+			}
+		}
+		if policy.ReferrerPolicy != "" {
+			h.Set("Referrer-Policy", policy.ReferrerPolicy)
+		}
+		if policy.PermissionsPolicy != "" {
+			h.Set("Permissions-Policy", policy.PermissionsPolicy)
+		}
+		if policy.FrameOptions != "" {
+			h.Set("X-Frame-Options", policy.FrameOptions)
+		}
+		if policy.HSTS != "" && requestOrigin(r).Scheme == "https" {
+			h.Set("Strict-Transport-Security", policy.HSTS)
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), cspNonceKey{}, nonce)))
+	})
+}
+
+// cspViolation is the part of a violation report worth logging.
+type cspViolation struct {
+	Document    string
+	Directive   string
+	Blocked     string
+	Source      string
+	Line        int
+	Sample      string
+	Disposition string
+}
+
+// legacyCSPReport is the body browsers POST to a report-uri.
+type legacyCSPReport struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		BlockedURI         string `json:"blocked-uri"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		ScriptSample       string `json:"script-sample"`
+		Disposition        string `json:"disposition"`
+	} `json:"csp-report"`
+}
+
+// reportingAPIReport is one entry of a Reporting API batch sent to a
+// report-to endpoint.
+type reportingAPIReport struct {
+	Type string `json:"type"`
+	Body struct {
+		DocumentURL        string `json:"documentURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		BlockedURL         string `json:"blockedURL"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		Sample             string `json:"sample"`
+		Disposition        string `json:"disposition"`
+	} `json:"body"`
+}
+
+var errCSPReport = errors.New("not a CSP violation report")
+
+// parseCSPReports decodes a report-uri body (one object) or a Reporting
+// API batch (an array), ignoring reports of other types.
+func parseCSPReports(body []byte) ([]cspViolation, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, errCSPReport
+	}
+
+	if body[0] == '[' {
+		var batch []reportingAPIReport
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, errCSPReport
+		}
+		var violations []cspViolation
+		for _, rep := range batch {
+			if rep.Type != "csp-violation" {
+				continue
+			}
+			b := rep.Body
+			violations = append(violations, cspViolation{b.DocumentURL, b.EffectiveDirective, b.BlockedURL, b.SourceFile, b.LineNumber, b.Sample, b.Disposition})
+		}
+		return violations, nil
+	}
+
+	var legacy legacyCSPReport
+	if err := json.Unmarshal(body, &legacy); err != nil || legacy.Report.DocumentURI == "" {
+		return nil, errCSPReport
+	}
+	rep := legacy.Report
+	directive := rep.EffectiveDirective
+	if directive == "" {
+		directive = rep.ViolatedDirective
+	}
+	return []cspViolation{{rep.DocumentURI, directive, rep.BlockedURI, rep.SourceFile, rep.LineNumber, rep.ScriptSample, rep.Disposition}}, nil
+}
+
+// cspReportHandler logs the Content-Security-Policy violations browsers
+// report, so a policy can be tightened or rolled out in report-only mode.
+func cspReportHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCSPReportBytes))
+	if err != nil {
+		http.Error(w, "Report too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	violations, err := parseCSPReports(body)
+	if err != nil {
+		http.Error(w, "Invalid CSP report", http.StatusBadRequest)
+		return
+	}
+
+	for _, v := range violations {
+		slog.Default().LogAttrs(r.Context(), slog.LevelWarn, "csp violation",
+			slog.String("document", v.Document),
+			slog.String("directive", v.Directive),
+			slog.String("blocked", v.Blocked),
+			slog.String("source", v.Source),
+			slog.Int("line", v.Line),
+			slog.String("sample", v.Sample),
+			slog.String("disposition", v.Disposition),
+			slog.String("request_id", requestIDFrom(r.Context())),
+		)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
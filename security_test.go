@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var nonceAttr = regexp.MustCompile(`<(script|style)( nonce="([^"]*)")?`)
+
+func TestCSPNonceCoversInlineElements(t *testing.T) {
+	for _, target := range []string{"/", "/wish/web/sam", "/nope", "/500"} {
+		rec := doRequest(t, http.MethodGet, target, nil)
+		csp := rec.Header().Get("Content-Security-Policy")
+		body := rec.Body.String()
+
+		matches := nonceAttr.FindAllStringSubmatch(body, -1)
+		if len(matches) == 0 {
+			t.Fatalf("%s: no inline <script> or <style> found", target)
+		}
+		for _, m := range matches {
+			if m[3] == "" || !strings.Contains(csp, "'nonce-"+m[3]+"'") {
+				t.Errorf("%s: <%s> nonce %q is not allowed by %q", target, m[1], m[3], csp)
+			}
+		}
+		if regexp.MustCompile(`\son[a-z]+=`).MatchString(body) {
+			t.Errorf("%s: page uses an inline event handler", target)
+		}
+	}
+
+	first := doRequest(t, http.MethodGet, "/", nil).Header().Get("Content-Security-Policy")
+	second := doRequest(t, http.MethodGet, "/", nil).Header().Get("Content-Security-Policy")
+	if first == second {
+		t.Error("two requests got the same nonce")
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	rec := doRequest(t, http.MethodGet, "/healthz", nil)
+	for header, want := range map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"X-Frame-Options":        "DENY",
+		"Referrer-Policy":        "strict-origin-when-cross-origin",
+		"Reporting-Endpoints":    `csp-endpoint="/csp-report"`,
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if rec.Header().Get("Permissions-Policy") == "" {
+		t.Error("Permissions-Policy is missing")
+	}
+	if rec.Header().Get("X-XSS-Protection") != "" {
+		t.Error("deprecated X-XSS-Protection is still sent")
+	}
+	if rec.Header().Get("Strict-Transport-Security") != "" {
+		t.Error("HSTS sent over plain HTTP")
+	}
+
+	rec = doRequest(t, http.MethodGet, "https://wish.example/healthz", nil)
+	if rec.Header().Get("Strict-Transport-Security") == "" {
+		t.Error("HSTS missing over TLS")
+	}
+}
+
+func TestSecurityHeadersConfig(t *testing.T) {
+	setConfig(t, func(c *config) {
+		c.SecurityHeaders.CSPReportOnly = true
+		c.SecurityHeaders.ContentSecurityPolicy = "default-src 'self'; script-src 'nonce-{nonce}'"
+		c.SecurityHeaders.FrameOptions = ""
+		c.SecurityHeaders.HSTS = ""
+	})
+	rec := doRequest(t, http.MethodGet, "https://wish.example/", nil)
+	if rec.Header().Get("Content-Security-Policy") != "" {
+		t.Error("enforced CSP sent in report-only mode")
+	}
+	if csp := rec.Header().Get("Content-Security-Policy-Report-Only"); !strings.HasPrefix(csp, "default-src 'self'; script-src 'nonce-") {
+		t.Errorf("Content-Security-Policy-Report-Only = %q", csp)
+	}
+	for _, header := range []string{"X-Frame-Options", "Strict-Transport-Security", "Reporting-Endpoints"} {
+		if got := rec.Header().Get(header); got != "" {
+			t.Errorf("%s = %q, want it left out", header, got)
+		}
+	}
+}
+
+func TestCSPReport(t *testing.T) {
+	var logs bytes.Buffer
+	saved := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(saved) })
+
+	tests := []struct {
+		contentType string
+		body        string
+		status      int
+	}{
+		{"application/csp-report", `{"csp-report":{"document-uri":"https://wish.example/","violated-directive":"script-src-elem","blocked-uri":"inline","line-number":12}}`, http.StatusNoContent},
+		{"application/reports+json", `[{"type":"csp-violation","body":{"documentURL":"https://wish.example/wish/web/sam","effectiveDirective":"img-src","blockedURL":"http://evil.example/x.png"}},{"type":"deprecation","body":{}}]`, http.StatusNoContent},
+		{"application/csp-report", `{"nope":1}`, http.StatusBadRequest},
+		{"application/csp-report", "", http.StatusBadRequest},
+		{"application/csp-report", `{"csp-report":{"document-uri":"` + strings.Repeat("a", maxCSPReportBytes) + `"}}`, http.StatusRequestEntityTooLarge},
+	}
+	h := newHandler(discardLogger())
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", tt.contentType)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != tt.status {
+			t.Errorf("report %.40q: status = %d, want %d", tt.body, rec.Code, tt.status)
+		}
+	}
+
+	for _, want := range []string{"directive=script-src-elem", "blocked=inline", "line=12", "directive=img-src", "blocked=http://evil.example/x.png"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log is missing %s:\n%s", want, logs.String())
+		}
+	}
+	if n := strings.Count(logs.String(), "csp violation"); n != 2 {
+		t.Errorf("logged %d violations, want 2", n)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// newServer returns an http.Server for handler with the configured
+// timeouts and header limit.
+func newServer(c config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              c.Listen,
+		Handler:           handler,
+		ReadTimeout:       c.ReadTimeout,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+	}
+}
+
+// serve accepts connections on ln until ctx is cancelled. It then reports
+// not-ready, keeps serving for c.ShutdownDelay so load balancers notice,
+// stops accepting new connections and drains in-flight requests for at
+// most c.ShutdownTimeout before closing the remaining ones.
+func serve(ctx context.Context, c config, srv *http.Server, ln net.Listener) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shuttingDown.Store(true)
+	if c.ShutdownDelay > 0 {
+		log.Printf("Not ready, waiting %s before shutting down\n", c.ShutdownDelay)
+		time.Sleep(c.ShutdownDelay)
+	}
+	log.Printf("Shutting down, draining connections for up to %s\n", c.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), c.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// run listens on the configured address and serves handler until SIGINT
+// or SIGTERM. A non-nil admin handler is served on the admin address; if
+// either server fails, both are shut down.
+func run(c config, handler, admin http.Handler) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ln, err := net.Listen("tcp", c.Listen)
+	if err != nil {
+		return err
+	}
+
+	adminErr := make(chan error, 1)
+	if admin != nil {
+		adminLn, err := net.Listen("tcp", c.AdminListen)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		log.Printf("Admin server starting on %s\n", adminLn.Addr())
+		go func() {
+			adminErr <- serve(ctx, c, newServer(c, admin), adminLn)
+			stop()
+		}()
+	} else {
+		adminErr <- nil
+	}
+
+	log.Printf("Server starting on %s\n", ln.Addr())
+	err = serve(ctx, c, newServer(c, handler), ln)
+	stop()
+	if aerr := <-adminErr; err == nil {
+		err = aerr
+	}
+	return err
+}
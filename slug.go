@@ -0,0 +1,291 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// generateSlug turns name into the lower-case, hyphen-separated form used
+// in share links. Letters and digits of every script are kept, along with
+// the combining marks that belong to them.
+func generateSlug(name string) string {
+	replacements := map[string]string{
+		"+":   " ",
+		"%20": " ",
+		"%25": "",
+	}
+
+	for old, new := range replacements {
+		name = strings.ReplaceAll(name, old, new)
+	}
+
+	var result []rune
+	previousWasHyphen := false
+
+	for _, r := range norm.NFC.String(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			result = append(result, unicode.ToLower(r))
+			previousWasHyphen = false
+		} else if unicode.IsMark(r) && len(result) > 0 && !previousWasHyphen {
+			// Vowel signs and viramas are part of the letter they follow.
+			result = append(result, r)
+		} else if unicode.IsSpace(r) || r == '-' {
+			if !previousWasHyphen {
+				result = append(result, '-')
+				previousWasHyphen = true
+			}
+		}
+	}
+
+	slug := string(result)
+	slug = strings.Trim(slug, "-")
+	return norm.NFC.String(slug)
+}
+
+// latinSpecial spells Latin letters that do not decompose into an ASCII
+// base letter plus accents.
+var latinSpecial = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d",
+	'þ': "th", 'ł': "l", 'ı': "i", 'ŋ': "ng", 'ħ': "h",
+}
+
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g", 'ў': "u", 'ј': "j",
+}
+
+var greek = map[rune]string{
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i",
+	'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y",
+	'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// abugida describes an Indic script, where a consonant carries an
+// inherent "a" that a vowel sign replaces and a virama removes.
+type abugida struct {
+	vowels     map[rune]string
+	consonants map[rune]string
+	signs      map[rune]string
+	virama     rune
+	// dropFinalA drops the inherent vowel at the end of a word, as spoken
+	// Hindi does ("राम" is Ram, not Rama).
+	dropFinalA bool
+}
+
+var tamil = &abugida{
+	vowels: map[rune]string{
+		'அ': "a", 'ஆ': "a", 'இ': "i", 'ஈ': "i", 'உ': "u", 'ஊ': "u",
+		'எ': "e", 'ஏ': "e", 'ஐ': "ai", 'ஒ': "o", 'ஓ': "o", 'ஔ': "au", 'ஃ': "h",
+	},
+	consonants: map[rune]string{
+		'க': "k", 'ங': "ng", 'ச': "s", 'ஞ': "nj", 'ட': "d", 'ண': "n",
+		'த': "th", 'ந': "n", 'ப': "p", 'ம': "m", 'ய': "y", 'ர': "r",
+		'ல': "l", 'வ': "v", 'ழ': "zh", 'ள': "l", 'ற': "r", 'ன': "n",
+		'ஜ': "j", 'ஷ': "sh", 'ஸ': "s", 'ஹ': "h",
+	},
+	signs: map[rune]string{
+		'ா': "a", 'ி': "i", 'ீ': "i", 'ு': "u", 'ூ': "u", 'ெ': "e",
+		'ே': "e", 'ை': "ai", 'ொ': "o", 'ோ': "o", 'ௌ': "au",
+	},
+	virama: '்',
+}
+
+var devanagari = &abugida{
+	vowels: map[rune]string{
+		'अ': "a", 'आ': "a", 'इ': "i", 'ई': "i", 'उ': "u", 'ऊ': "u",
+		'ऋ': "ri", 'ए': "e", 'ऐ': "ai", 'ओ': "o", 'औ': "au",
+		'ं': "n", 'ँ': "n", 'ः': "h",
+	},
+	consonants: map[rune]string{
+		'क': "k", 'ख': "kh", 'ग': "g", 'घ': "gh", 'ङ': "n", 'च': "ch",
+		'छ': "chh", 'ज': "j", 'झ': "jh", 'ञ': "n", 'ट': "t", 'ठ': "th",
+		'ड': "d", 'ढ': "dh", 'ण': "n", 'त': "t", 'थ': "th", 'द': "d",
+		'ध': "dh", 'न': "n", 'प': "p", 'फ': "ph", 'ब': "b", 'भ': "bh",
+		'म': "m", 'य': "y", 'र': "r", 'ल': "l", 'व': "v", 'श': "sh",
+		'ष': "sh", 'स': "s", 'ह': "h",
+	},
+	signs: map[rune]string{
+		'ा': "a", 'ि': "i", 'ी': "i", 'ु': "u", 'ू': "u", 'ृ': "ri",
+		'े': "e", 'ै': "ai", 'ो': "o", 'ौ': "au",
+	},
+	virama:     '्',
+	dropFinalA: true,
+}
+
+var abugidas = []*abugida{tamil, devanagari}
+
+func findAbugida(r rune) *abugida {
+	for _, a := range abugidas {
+		if _, ok := a.consonants[r]; ok {
+			return a
+		}
+		if _, ok := a.vowels[r]; ok {
+			return a
+		}
+	}
+	return nil
+}
+
+// transliterateRune spells a single character in ASCII, or returns "" when
+// there is no sensible spelling.
+func transliterateRune(r rune) string {
+	if r <= unicode.MaxASCII {
+		return string(r)
+	}
+	r = unicode.ToLower(r)
+	if s, ok := latinSpecial[r]; ok {
+		return s
+	}
+	if s, ok := cyrillic[r]; ok {
+		return s
+	}
+	if s, ok := greek[r]; ok {
+		return s
+	}
+
+	// Accented letters decompose into a base letter and combining marks.
+	decomposed := norm.NFD.String(string(r))
+	if decomposed == string(r) {
+		return ""
+	}
+	var b strings.Builder
+	for _, part := range decomposed {
+		if !unicode.IsMark(part) {
+			b.WriteString(transliterateRune(part))
+		}
+	}
+	return b.String()
+}
+
+// transliterate spells name in ASCII: Latin letters lose their accents,
+// Cyrillic, Greek, Tamil and Devanagari are romanized, and characters of
+// other scripts are dropped.
+func transliterate(name string) string {
+	runes := []rune(norm.NFC.String(name))
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		script := findAbugida(r)
+		if script == nil {
+			b.WriteString(transliterateRune(r))
+			continue
+		}
+		if v, ok := script.vowels[r]; ok {
+			b.WriteString(v)
+			continue
+		}
+
+		consonant := script.consonants[r]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		// A doubled digraph is spelled once: கார்த்திக் is karthik.
+		if next == script.virama && len(consonant) > 1 && i+2 < len(runes) && runes[i+2] == r {
+			i++
+			continue
+		}
+		b.WriteString(consonant)
+		switch {
+		case next == script.virama:
+			i++
+		case script.signs[next] != "":
+			b.WriteString(script.signs[next])
+			i++
+		case script.dropFinalA && !isInScript(script, next):
+		default:
+			b.WriteString("a")
+		}
+	}
+	return b.String()
+}
+
+// isInScript reports whether r continues a word written in script.
+func isInScript(script *abugida, r rune) bool {
+	_, consonant := script.consonants[r]
+	_, vowel := script.vowels[r]
+	return consonant || vowel || r == script.virama || script.signs[r] != ""
+}
+
+// slugFor returns the slug used in share links for name. With ascii set,
+// the name is transliterated first; names with nothing to transliterate
+// keep their Unicode slug rather than losing it entirely.
+func slugFor(name string, ascii bool) string {
+	if ascii {
+		if slug := generateSlug(transliterate(name)); slug != "" {
+			return slug
+		}
+	}
+	return generateSlug(name)
+}
+
+// shareURL returns the canonical link to the web view for slug.
+func shareURL(baseURL, slug string) string {
+	return baseURL + "/wish/web/" + url.PathEscape(slug)
+}
+
+// textURL returns the canonical link to the plain text greeting for slug.
+func textURL(baseURL, slug string) string {
+	return baseURL + "/wish/text/" + url.PathEscape(slug)
+}
+
+// apiSlug is the JSON representation returned by /api/v1/slug.
+type apiSlug struct {
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	ASCII    bool   `json:"ascii"`
+	ShareURL string `json:"share_url"`
+}
+
+// apiSlugHandler returns the slug and share link for a name, so forms and
+// API clients never have to reimplement slug generation.
+func apiSlugHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeAPIError(w, http.StatusBadRequest, "name_required", "Name is required")
+		return
+	}
+
+	validName, err := validateName(name)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, nameErrorCode(err), err.Error())
+		return
+	}
+
+	ascii := cfg.TransliterateSlugs
+	if v := r.URL.Query().Get("transliterate"); v != "" {
+		ascii, err = strconv.ParseBool(v)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid_transliterate", "transliterate must be true or false")
+			return
+		}
+	}
+
+	slug := slugFor(validName, ascii)
+	writeJSON(w, http.StatusOK, apiSlug{
+		Name:     cleanName(validName),
+		Slug:     slug,
+		ASCII:    isASCII(slug),
+		ShareURL: shareURL(publicBaseURL(r), slug),
+	})
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
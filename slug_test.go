@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestGenerateSlug(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Sam", "sam"},
+		{"Mary Jane", "mary-jane"},
+		{"  Mary   Jane  ", "mary-jane"},
+		{"Mary-Jane", "mary-jane"},
+		{"Mary - Jane", "mary-jane"},
+		{"Tom & Jerry", "tom-jerry"},
+		{"Tom+Jerry", "tom-jerry"},
+		{"Tom%20Jerry", "tom-jerry"},
+		{"100%25", "100"},
+		{"R2-D2", "r2-d2"},
+		{"José", "josé"},
+		{"Zoë\tAnn", "zoë-ann"},
+		{"!!!", ""},
+		{"-Sam-", "sam"},
+		{"அருண்", "அருண்"},
+		{"Jose\u0301", "josé"},
+		{"a \u0301b", "a-b"},
+	}
+	for _, tt := range tests {
+		if got := generateSlug(tt.name); got != tt.want {
+			t.Errorf("generateSlug(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"José María", "jose-maria"},
+		{"Straße", "strasse"},
+		{"Łukasz Øster", "lukasz-oster"},
+		{"Алиса", "alisa"},
+		{"Σωκράτης", "sokratis"},
+		{"அருண்", "arun"},
+		{"ஆனந்த்", "ananth"},
+		{"கார்த்திக்", "karthik"},
+		{"राम", "ram"},
+		{"प्रिया", "priya"},
+		{"Sam", "sam"},
+		{"山田", "山田"},
+	}
+	for _, tt := range tests {
+		if got := slugFor(tt.name, true); got != tt.want {
+			t.Errorf("slugFor(%q, true) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestShareURLEscapesSlug(t *testing.T) {
+	if got := shareURL("https://wish.example", "josé"); got != "https://wish.example/wish/web/jos%C3%A9" {
+		t.Errorf("shareURL = %q", got)
+	}
+}
+
+func TestAPISlug(t *testing.T) {
+	tests := []struct {
+		query string
+		slug  string
+		ascii bool
+	}{
+		{"name=Tom+%26+Jerry", "tom-jerry", true},
+		{"name=Jos%C3%A9", "josé", false},
+		{"name=Jos%C3%A9&transliterate=1", "jose", true},
+		{"name=%E0%AE%85%E0%AE%B0%E0%AF%81%E0%AE%A3%E0%AF%8D&transliterate=true", "arun", true},
+	}
+	for _, tt := range tests {
+		rec := doRequest(t, http.MethodGet, "https://wish.example/api/v1/slug?"+tt.query, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d; body: %s", tt.query, rec.Code, rec.Body)
+		}
+		var got apiSlug
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Slug != tt.slug || got.ASCII != tt.ascii {
+			t.Errorf("%s: slug = %q, ascii = %v, want %q, %v", tt.query, got.Slug, got.ASCII, tt.slug, tt.ascii)
+		}
+		if want := shareURL("https://wish.example", tt.slug); got.ShareURL != want {
+			t.Errorf("%s: share_url = %q, want %q", tt.query, got.ShareURL, want)
+		}
+	}
+
+	for _, query := range []string{"", "name=Sam&transliterate=maybe", "name=%00"} {
+		if rec := doRequest(t, http.MethodGet, "/api/v1/slug?"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestSlugIsTheSameFromEveryEntryPoint(t *testing.T) {
+	name := "Tom & Jerry"
+	want := "/wish/web/tom-jerry"
+	for _, target := range []string{"/wish?name=Tom+%26+Jerry", "/api/v1/wish?name=Tom+%26+Jerry", "/api/v1/slug?name=Tom+%26+Jerry"} {
+		rec := doRequest(t, http.MethodGet, target, nil)
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("%s does not link to %s for %q:\n%s", target, want, name, rec.Body)
+		}
+	}
+}
+
+func FuzzGenerateSlug(f *testing.F) {
+	for _, seed := range []string{"Sam", "Tom & Jerry", "Mary - Jane", " -a- ", "José", "அருண்", "%20%25+", "a b"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		slug := generateSlug(name)
+		if strings.Contains(slug, "--") {
+			t.Errorf("generateSlug(%q) = %q contains --", name, slug)
+		}
+		if strings.HasPrefix(slug, "-") || strings.HasSuffix(slug, "-") {
+			t.Errorf("generateSlug(%q) = %q has a leading or trailing hyphen", name, slug)
+		}
+		if !utf8.ValidString(slug) || !norm.NFC.IsNormalString(slug) {
+			t.Errorf("generateSlug(%q) = %q is not NFC UTF-8", name, slug)
+		}
+		for _, r := range slug {
+			if r != '-' && !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r) {
+				t.Errorf("generateSlug(%q) = %q contains %q", name, slug, r)
+			}
+		}
+		if again := generateSlug(slug); again != slug {
+			t.Errorf("generateSlug is not idempotent: %q -> %q -> %q", name, slug, again)
+		}
+	})
+}
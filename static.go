@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed static
+var embeddedStatic embed.FS
+
+// staticTypes are the content types of the files below static/. They are
+// listed here rather than taken from the system MIME table so responses
+// are the same on every host.
+var staticTypes = map[string]string{
+	".css":   "text/css; charset=utf-8",
+	".svg":   "image/svg+xml",
+	".png":   "image/png",
+	".ico":   "image/x-icon",
+	".ttf":   "font/ttf",
+	".woff2": "font/woff2",
+	".txt":   "text/plain; charset=utf-8",
+}
+
+// staticAsset is a file served below /static/, with a gzip variant
+// compressed once at startup.
+type staticAsset struct {
+	hashedName  string
+	contentType string
+	etag        string
+	body        []byte
+	gzipped     []byte // nil when compression does not make the file smaller
+}
+
+// staticFiles indexes the assets by their plain and content-hashed names,
+// both relative to /static/.
+type staticFiles struct {
+	byName   map[string]*staticAsset
+	byHashed map[string]*staticAsset
+}
+
+var staticAssets = mustLoadStatic()
+
+// loadStatic reads every file of fsys. References from stylesheets to
+// other assets ("/static/fonts/poppins/Poppins-Regular.ttf") are
+// rewritten to the hashed names, so changing a font also changes the
+// stylesheet's name. Relative references, as in the vendored Font Awesome
+// stylesheet, are left alone and served under the plain names.
+func loadStatic(fsys fs.FS) (*staticFiles, error) {
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			names = append(names, name)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := &staticFiles{byName: make(map[string]*staticAsset), byHashed: make(map[string]*staticAsset)}
+	var stylesheets []string
+	for _, name := range names {
+		if path.Ext(name) == ".css" {
+			stylesheets = append(stylesheets, name)
+			continue
+		}
+		if err := files.add(fsys, name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	refs := make([]string, 0, 2*len(files.byName))
+	for name, a := range files.byName {
+		refs = append(refs, "/static/"+name, "/static/"+a.hashedName)
+	}
+	rewrite := strings.NewReplacer(refs...)
+	for _, name := range stylesheets {
+		if err := files.add(fsys, name, rewrite); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func (files *staticFiles) add(fsys fs.FS, name string, rewrite *strings.Replacer) error {
+	body, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	if rewrite != nil {
+		body = []byte(rewrite.Replace(string(body)))
+	}
+
+	ext := path.Ext(name)
+	contentType, ok := staticTypes[ext]
+	if !ok {
+		return &fs.PathError{Op: "serve", Path: name, Err: fs.ErrInvalid}
+	}
+
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:5])
+	a := &staticAsset{
+		hashedName:  strings.TrimSuffix(name, ext) + "." + hash + ext,
+		contentType: contentType,
+		etag:        `"` + hash + `"`,
+		body:        body,
+		gzipped:     gzipBytes(body),
+	}
+	files.byName[name] = a
+	files.byHashed[a.hashedName] = a
+	return nil
+}
+
+// gzipBytes compresses b, returning nil when that saves less than a tenth.
+func gzipBytes(b []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write(b)
+	zw.Close()
+	if buf.Len() > len(b)*9/10 {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+func mustLoadStatic() *staticFiles {
+	sub, err := fs.Sub(embeddedStatic, "static")
+	if err != nil {
+		log.Fatalf("Failed to load static files: %v", err)
+	}
+	files, err := loadStatic(sub)
+	if err != nil {
+		log.Fatalf("Failed to load static files: %v", err)
+	}
+	return files
+}
+
+// assetPath returns the content-hashed URL of a static file, for use in
+// templates as {{asset "css/fonts.css"}}. Unknown names are returned
+// unhashed so a typo shows up as a 404 rather than a template error.
+func assetPath(name string) string {
+	if a, ok := staticAssets.byName[name]; ok {
+		return "/static/" + a.hashedName
+	}
+	return "/static/" + name
+}
+
+// staticHandler serves /static/. Hashed names never change content and are
+// cached for a year; plain names are revalidated with their ETag.
+func staticHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("path")
+	if a, ok := staticAssets.byHashed[name]; ok {
+		serveAsset(w, r, a, "public, max-age=31536000, immutable")
+		return
+	}
+	if a, ok := staticAssets.byName[name]; ok {
+		serveAsset(w, r, a, "no-cache")
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// faviconHandler serves a favicon at the path browsers request by default.
+func faviconHandler(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveAsset(w, r, staticAssets.byName[name], "public, max-age=86400")
+	}
+}
+
+// serveAsset writes a, gzipped when the client accepts it. ServeContent
+// answers conditional, range and HEAD requests.
+func serveAsset(w http.ResponseWriter, r *http.Request, a *staticAsset, cacheControl string) {
+	h := w.Header()
+	h.Set("Content-Type", a.contentType)
+	h.Set("Cache-Control", cacheControl)
+
+	body, etag := a.body, a.etag
+	if a.gzipped != nil {
+		h.Add("Vary", "Accept-Encoding")
+		if acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			body, etag = a.gzipped, strings.TrimSuffix(a.etag, `"`)+`-gzip"`
+			h.Set("Content-Encoding", "gzip")
+		}
+	}
+	h.Set("ETag", etag)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip,
+// either by name or through "*". An explicit q=0 refuses it.
+func acceptsGzip(header string) bool {
+	gzipQ, anyQ := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		q := 1.0
+		for _, p := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.ToLower(strings.TrimSpace(key)) != "q" {
+				continue
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || v < 0 || v > 1 {
+				v = 0
+			}
+			q = v
+		}
+		switch coding {
+		case "gzip", "x-gzip":
+			gzipQ = q
+		case "*":
+			anyQ = q
+		}
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return anyQ > 0
+}
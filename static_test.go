@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStaticHashedAssets(t *testing.T) {
+	for name, a := range staticAssets.byName {
+		rec := doRequest(t, http.MethodGet, assetPath(name), nil)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200", assetPath(name), rec.Code)
+			continue
+		}
+		if cc := rec.Header().Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+			t.Errorf("%s: Cache-Control = %q, want immutable", name, cc)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != a.contentType {
+			t.Errorf("%s: Content-Type = %q, want %q", name, ct, a.contentType)
+		}
+		if !bytes.Equal(rec.Body.Bytes(), a.body) {
+			t.Errorf("%s: body differs from the embedded file", name)
+		}
+	}
+}
+
+func TestStaticGzip(t *testing.T) {
+	target := assetPath("css/fonts.css")
+	rec := doRequest(t, http.MethodGet, target, http.Header{"Accept-Encoding": {"br, gzip"}})
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", rec.Header().Get("Content-Encoding"))
+	}
+	if vary := rec.Header().Get("Vary"); vary != "Accept-Encoding" {
+		t.Errorf("Vary = %q, want Accept-Encoding", vary)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(body, staticAssets.byName["css/fonts.css"].body) {
+		t.Error("decompressed body differs from the stylesheet")
+	}
+
+	rec = doRequest(t, http.MethodGet, target, http.Header{"Accept-Encoding": {"gzip;q=0, *"}})
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Error("gzip was sent to a client refusing it")
+	}
+}
+
+func TestStaticRevalidation(t *testing.T) {
+	rec := doRequest(t, http.MethodGet, "/static/css/fonts.css", nil)
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Cache-Control for an unhashed name = %q, want no-cache", cc)
+	}
+	etag := rec.Header().Get("ETag")
+	rec = doRequest(t, http.MethodGet, "/static/css/fonts.css", http.Header{"If-None-Match": {etag}})
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("status with a matching ETag = %d, want 304", rec.Code)
+	}
+}
+
+func TestStylesheetsReferenceHashedAssets(t *testing.T) {
+	files, err := loadStatic(fstest.MapFS{
+		"css/fonts.css":             {Data: []byte(`src: url("/static/fonts/a/A.ttf"); src: url("../fonts/a/A.ttf");`)},
+		"fonts/a/A.ttf":             {Data: []byte("font")},
+		"vendor/x/css/x.css":        {Data: []byte(`src: url(../webfonts/x.woff2);`)},
+		"vendor/x/webfonts/x.woff2": {Data: []byte("webfont")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	font := "/static/" + files.byName["fonts/a/A.ttf"].hashedName
+	fonts := string(files.byName["css/fonts.css"].body)
+	if want := `src: url("` + font + `"); src: url("../fonts/a/A.ttf");`; fonts != want {
+		t.Errorf("fonts.css = %s, want %s", fonts, want)
+	}
+	if x := string(files.byName["vendor/x/css/x.css"].body); x != `src: url(../webfonts/x.woff2);` {
+		t.Errorf("relative reference was rewritten: %s", x)
+	}
+}
+
+// assetRefPattern matches the /static/ URLs pages and stylesheets link to.
+var assetRefPattern = regexp.MustCompile(`(?:href|src)="(/static/[^"]+)"|url\("(/static/[^"]+)"\)`)
+
+func TestPagesReferenceEmbeddedAssets(t *testing.T) {
+	if _, ok := staticAssets.byName["vendor/bulma/css/bulma.min.css"]; !ok {
+		t.Skip("vendored assets are missing, run make static")
+	}
+	bodies := map[string]string{"css/fonts.css": string(staticAssets.byName["css/fonts.css"].body)}
+	for _, target := range []string{"/", "/wish/web/sam", "/nope"} {
+		bodies[target] = doRequest(t, http.MethodGet, target, nil).Body.String()
+	}
+	for source, body := range bodies {
+		for _, m := range assetRefPattern.FindAllStringSubmatch(body, -1) {
+			ref := strings.TrimPrefix(m[1]+m[2], "/static/")
+			if _, ok := staticAssets.byHashed[ref]; !ok {
+				t.Errorf("%s links to %s, which is not an embedded asset", source, m[1]+m[2])
+			}
+		}
+	}
+}
+
+func TestPagesUseNoExternalAssets(t *testing.T) {
+	for _, target := range []string{"/", "/wish/web/sam", "/nope"} {
+		body := doRequest(t, http.MethodGet, target, nil).Body.String()
+		for _, host := range []string{"cdnjs.cloudflare.com", "fonts.googleapis.com", "fonts.gstatic.com"} {
+			if strings.Contains(body, host) {
+				t.Errorf("%s loads assets from %s", target, host)
+			}
+		}
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"GZIP", true},
+		{"*", true},
+		{"gzip;q=0", false},
+		{"gzip;q=0, *", false},
+		{"br", false},
+	}
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.header); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
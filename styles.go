@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+const defaultStyle = "friend"
+
+var errUnknownStyle = errors.New("unknown style")
+
+// artOptions carries the per-request settings shared by all art styles.
+type artOptions struct {
+	Font  *figFont
+	Quote quote
+	// Style names the requested style counted in wish_style_total once
+	// the greeting is rendered; empty for art no style was asked for.
+	Style string
+}
+
+// artRenderer draws the art block of a greeting for a name.
+type artRenderer interface {
+	Render(name string, opts artOptions) string
+}
+
+// artRendererFunc adapts a plain function to the artRenderer interface.
+type artRendererFunc func(name string, opts artOptions) string
+
+func (f artRendererFunc) Render(name string, opts artOptions) string {
+	return f(name, opts)
+}
+
+// artStyle is a named entry of the style registry.
+type artStyle struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	renderer    artRenderer
+}
+
+// artStyles is the registry of selectable art styles, in listing order.
+var artStyles = []artStyle{
+	{"friend", "The name in FIGlet letters above the FANTASTIC FRIEND banner", artRendererFunc(friendStyle)},
+	{"heart", "The name framed inside an ASCII heart", artRendererFunc(heartStyle)},
+	{"card", "A greeting card drawn with box-drawing characters", artRendererFunc(cardStyle)},
+	{"cowsay", "A cow wishing your friend in a speech bubble", artRendererFunc(cowsayStyle)},
+}
+
+// findStyle returns the registered style matching name case-insensitively,
+// or the default style for an empty name.
+func findStyle(name string) (artStyle, bool) {
+	if name == "" {
+		name = defaultStyle
+	}
+	for _, s := range artStyles {
+		if strings.EqualFold(s.Name, name) {
+			return s, true
+		}
+	}
+	return artStyle{}, false
+}
+
+// lookupStyle returns the named style, or the default when name is empty.
+func lookupStyle(name string) (artRenderer, error) {
+	if s, ok := findStyle(name); ok {
+		return s.renderer, nil
+	}
+
+	names := make([]string, len(artStyles))
+	for i, s := range artStyles {
+		names[i] = s.Name
+	}
+	return nil, fmt.Errorf("%w %q (available: %s)", errUnknownStyle, name, strings.Join(names, ", "))
+}
+
+// artFromRequest resolves the style, font and quote query parameters for
+// a greeting to name.
+func artFromRequest(r *http.Request, name string) (artRenderer, artOptions, error) {
+	style, err := lookupStyle(r.URL.Query().Get("style"))
+	if err != nil {
+		return nil, artOptions{}, err
+	}
+	font, err := lookupFont(r.URL.Query().Get("font"))
+	if err != nil {
+		return nil, artOptions{}, err
+	}
+	q, err := quoteFromRequest(r, name)
+	if err != nil {
+		return nil, artOptions{}, err
+	}
+	opts := artOptions{Font: font, Quote: q}
+	if s, ok := findStyle(r.URL.Query().Get("style")); ok {
+		opts.Style = s.Name
+	}
+	return style, opts, nil
+}
+
+// stylesHandler lists the available art styles.
+func stylesHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"default": defaultStyle,
+		"styles":  artStyles,
+	})
+}
+
+func friendStyle(name string, opts artOptions) string {
+	return greetingArt(name, opts.Font)
+}
+
+// displayWidth returns the number of terminal columns s occupies: wide
+// characters such as CJK and most emoji take two, combining marks none.
+func displayWidth(s string) int {
+	return uniseg.StringWidth(s)
+}
+
+// centerText pads s with spaces to width columns, centering it.
+func centerText(s string, width int) string {
+	gap := width - displayWidth(s)
+	if gap <= 0 {
+		return s
+	}
+	return strings.Repeat(" ", gap/2) + s + strings.Repeat(" ", gap-gap/2)
+}
+
+// heartStyle fills the classic heart curve (x²+y²-1)³ - x²y³ <= 0 with
+// stars and writes the name across its widest rows.
+func heartStyle(name string, opts artOptions) string {
+	label := " " + cleanName(name) + " "
+	width := max(32, displayWidth(label)+10)
+	width += width % 2
+	height := width / 2
+
+	rows := make([][]rune, height)
+	for j := range rows {
+		y := 1.2 - 2.25*float64(j)/float64(height-1)
+		row := make([]rune, width)
+		for i := range row {
+			x := -1.2 + 2.4*float64(i)/float64(width-1)
+			if math.Pow(x*x+y*y-1, 3)-x*x*y*y*y <= 0 {
+				row[i] = '*'
+			} else {
+				row[i] = ' '
+			}
+		}
+		rows[j] = row
+	}
+
+	// Every cell is one column wide, so the label replaces as many cells as
+	// it takes columns.
+	lines := make([]string, height)
+	for j, row := range rows {
+		lines[j] = string(row)
+	}
+	mid := height / 3
+	labelWidth := displayWidth(label)
+	start := (width - labelWidth) / 2
+	lines[mid] = string(rows[mid][:start]) + label + string(rows[mid][start+labelWidth:])
+
+	var b strings.Builder
+	b.WriteByte('\n')
+	for _, row := range lines {
+		line := strings.TrimRight(row, " ")
+		if line == "" {
+			continue
+		}
+		b.WriteString(" " + line + "\n")
+	}
+	return b.String()
+}
+
+// cardStyle draws a rounded greeting card around the name.
+func cardStyle(name string, opts artOptions) string {
+	lines := []string{
+		"",
+		"Happy Friendship Day",
+		"",
+		cleanName(name),
+		"",
+		"★  You are a fantastic friend  ★",
+		"",
+	}
+
+	width := 0
+	for _, l := range lines {
+		width = max(width, displayWidth(l))
+	}
+	width += 6
+
+	var b strings.Builder
+	b.WriteString("\n ╭" + strings.Repeat("─", width) + "╮\n")
+	for _, l := range lines {
+		b.WriteString(" │" + centerText(l, width) + "│\n")
+	}
+	b.WriteString(" ╰" + strings.Repeat("─", width) + "╯\n")
+	return b.String()
+}
+
+const cow = `        \   ^__^
+         \  (oo)\_______
+            (__)\       )\/\
+                ||----w |
+                ||     ||
+`
+
+// cowsayStyle puts the wish in a cowsay speech bubble, wrapping long
+// messages the way cowsay does.
+func cowsayStyle(name string, opts artOptions) string {
+	message := "Happy Friendship Day, " + cleanName(name) + "! You are a fantastic friend."
+	lines := wrapWords(message, 40)
+
+	width := 0
+	for _, l := range lines {
+		width = max(width, displayWidth(l))
+	}
+
+	var b strings.Builder
+	b.WriteString("\n  " + strings.Repeat("_", width+2) + "\n")
+	for i, l := range lines {
+		left, right := "|", "|"
+		switch {
+		case len(lines) == 1:
+			left, right = "<", ">"
+		case i == 0:
+			left, right = "/", "\\"
+		case i == len(lines)-1:
+			left, right = "\\", "/"
+		}
+		b.WriteString(" " + left + " " + l + strings.Repeat(" ", width-displayWidth(l)) + " " + right + "\n")
+	}
+	b.WriteString("  " + strings.Repeat("-", width+2) + "\n")
+	b.WriteString(cow)
+	return b.String()
+}
+
+// wrapWords splits s into lines of at most width columns, breaking on spaces.
+// Words longer than width are kept whole on their own line.
+func wrapWords(s string, width int) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(s) {
+		switch {
+		case line == "":
+			line = word
+		case displayWidth(line)+1+displayWidth(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
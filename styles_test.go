@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLookupStyle(t *testing.T) {
+	for _, name := range []string{"", "friend", "HEART", "card", "cowsay"} {
+		if _, err := lookupStyle(name); err != nil {
+			t.Errorf("lookupStyle(%q) = %v", name, err)
+		}
+	}
+	if _, err := lookupStyle("nope"); !errors.Is(err, errUnknownStyle) {
+		t.Errorf("lookupStyle(nope) error = %v, want errUnknownStyle", err)
+	}
+}
+
+func TestLookupFont(t *testing.T) {
+	for _, name := range fontNames() {
+		if _, err := lookupFont(name); err != nil {
+			t.Errorf("lookupFont(%q) = %v", name, err)
+		}
+	}
+	if f, err := lookupFont(""); err != nil || f.name != defaultFont {
+		t.Errorf("lookupFont(\"\") = %v, %v, want the default font", f, err)
+	}
+	if _, err := lookupFont("nope"); !errors.Is(err, errUnknownFont) {
+		t.Errorf("lookupFont(nope) error = %v, want errUnknownFont", err)
+	}
+}
+
+func TestStyleCountedWhenRendered(t *testing.T) {
+	count := func() float64 {
+		styleUsage.mu.Lock()
+		defer styleUsage.mu.Unlock()
+		return styleUsage.values[labelKey([]string{"heart"})]
+	}
+	tests := []struct {
+		target string
+		want   float64
+	}{
+		{"/wish/text/sam?style=heart", 1},
+		{"/wish/text/sam?style=heart&palette=bogus", 0},
+		{"/wish/text/sam?style=heart&color=lots", 0},
+		{"/wish/svg?name=Sam&style=heart&theme=bogus", 0},
+		{"/wish/svg?name=Sam&style=heart&bg=red", 0},
+		{"/wish/svg?name=Sam&style=heart", 1},
+	}
+	for _, tt := range tests {
+		before := count()
+		doRequest(t, http.MethodGet, tt.target, nil)
+		if got := count() - before; got != tt.want {
+			t.Errorf("%s counted the heart style %v times, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestStylesRenderName(t *testing.T) {
+	font, _ := lookupFont("")
+	opts := artOptions{Font: font, Quote: quoteFor("Sam")}
+	for _, s := range artStyles {
+		art := s.renderer.Render("Sam", opts)
+		if strings.TrimSpace(art) == "" {
+			t.Errorf("style %s rendered nothing", s.Name)
+		}
+		if s.Name != "friend" && !strings.Contains(art, "Sam") {
+			t.Errorf("style %s does not show the name:\n%s", s.Name, art)
+		}
+	}
+}
+
+func TestCardStyleLinesAreAligned(t *testing.T) {
+	font, _ := lookupFont("")
+	art := cardStyle("Mary Jane", artOptions{Font: font, Quote: quoteFor("Mary Jane")})
+	var width int
+	for _, line := range strings.Split(strings.Trim(art, "\n"), "\n") {
+		line = strings.TrimRight(line, " ")
+		n := utf8.RuneCountInString(line)
+		if width == 0 {
+			width = n
+		}
+		if n != width {
+			t.Fatalf("card line %q is %d wide, want %d:\n%s", line, n, width, art)
+		}
+	}
+}
+
+func TestFigletRenderWraps(t *testing.T) {
+	font, _ := lookupFont("standard")
+	out := font.Render("Friendship Forever Always", 60)
+	for _, line := range strings.Split(out, "\n") {
+		if n := utf8.RuneCountInString(line); n > 60 {
+			t.Errorf("line is %d wide, want at most 60: %q", n, line)
+		}
+	}
+	if lines := strings.Count(strings.TrimRight(out, "\n"), "\n") + 1; lines <= font.height {
+		t.Errorf("rendered %d lines, want the text wrapped onto several rows", lines)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// SVG layout metrics for a 14px monospace font.
+const (
+	svgFontSize   = 14
+	svgLineHeight = 18
+	svgCharWidth  = 8.4
+	svgPadding    = 24
+)
+
+var errUnknownTheme = errors.New("unknown theme")
+
+var hexColorPattern = regexp.MustCompile(`^#?[0-9a-fA-F]{6}$`)
+
+// svgTheme is a named color palette for SVG output.
+type svgTheme struct {
+	Name       string
+	Background string
+	Foreground string
+	Accent     string
+	Quote      string
+}
+
+// svgThemes lists the built-in palettes; the first one is the default.
+var svgThemes = []svgTheme{
+	{"terminal", "#3d3d3d", "#ecf0f1", "#25d366", "#D6A2E8"},
+	{"friendship", "#58B19F", "#2C3A47", "#FD7272", "#f5f6fa"},
+	{"lavender", "#D6A2E8", "#2C3A47", "#6D214F", "#2C3A47"},
+	{"light", "#ffffff", "#2C3A47", "#58B19F", "#666666"},
+}
+
+// svgThemeFromRequest resolves the theme query parameter and applies the
+// optional bg, fg and accent hex color overrides.
+func svgThemeFromRequest(r *http.Request) (svgTheme, error) {
+	query := r.URL.Query()
+	theme := svgThemes[0]
+	if name := query.Get("theme"); name != "" {
+		found := false
+		for _, t := range svgThemes {
+			if strings.EqualFold(t.Name, name) {
+				theme, found = t, true
+				break
+			}
+		}
+		if !found {
+			return svgTheme{}, fmt.Errorf("%w %q", errUnknownTheme, name)
+		}
+	}
+
+	overrides := []struct {
+		param string
+		color *string
+	}{
+		{"bg", &theme.Background},
+		{"fg", &theme.Foreground},
+		{"accent", &theme.Accent},
+	}
+	for _, o := range overrides {
+		value := query.Get(o.param)
+		if value == "" {
+			continue
+		}
+		if !hexColorPattern.MatchString(value) {
+			return svgTheme{}, fmt.Errorf("%s must be a hex color like #58B19F", o.param)
+		}
+		*o.color = "#" + strings.TrimPrefix(value, "#")
+	}
+	return theme, nil
+}
+
+// xmlEscape escapes s for use in XML text and attribute values.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// artRows splits art into lines with trailing whitespace removed and
+// surrounding blank lines dropped.
+func artRows(art string) []string {
+	rows := strings.Split(art, "\n")
+	for i, row := range rows {
+		rows[i] = strings.TrimRight(row, " \t")
+	}
+	for len(rows) > 0 && rows[0] == "" {
+		rows = rows[1:]
+	}
+	for len(rows) > 0 && rows[len(rows)-1] == "" {
+		rows = rows[:len(rows)-1]
+	}
+	return rows
+}
+
+// renderSVG lays out the greeting as monospace text rows: a heading with
+// the name, the art block and the quote. All text is XML-escaped here and
+// nowhere else.
+func renderSVG(g greeting, theme svgTheme) string {
+	type row struct {
+		text  string
+		color string
+		attrs string
+	}
+
+	rows := []row{{text: "Happy Friendship Day, " + g.Name + "!", color: theme.Accent, attrs: ` font-weight="bold"`}, {}}
+	for _, line := range artRows(g.Art) {
+		rows = append(rows, row{text: line, color: theme.Foreground})
+	}
+	rows = append(rows, row{})
+	for _, line := range strings.Split(g.Quote.Text, "\n") {
+		rows = append(rows, row{text: line, color: theme.Quote, attrs: ` font-style="italic"`})
+	}
+
+	cols := 0
+	for _, r := range rows {
+		cols = max(cols, displayWidth(r.text))
+	}
+	width := int(float64(cols)*svgCharWidth) + 2*svgPadding
+	height := len(rows)*svgLineHeight + 2*svgPadding
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" role="img">`+"\n", width, height, width, height)
+	fmt.Fprintf(&b, "<title>%s</title>\n", xmlEscape(g.Name+" : Happy Friendship Wishes"))
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" rx="10" fill="%s"/>`+"\n", theme.Background)
+	fmt.Fprintf(&b, `<g font-family="ui-monospace, SFMono-Regular, Menlo, Consolas, monospace" font-size="%d" xml:space="preserve">`+"\n", svgFontSize)
+	for i, r := range rows {
+		if r.text == "" {
+			continue
+		}
+		y := svgPadding + (i+1)*svgLineHeight - (svgLineHeight-svgFontSize)/2
+		fmt.Fprintf(&b, `<text x="%d" y="%d" fill="%s"%s>%s</text>`+"\n", svgPadding, y, r.color, r.attrs, xmlEscape(r.text))
+	}
+	b.WriteString("</g>\n</svg>\n")
+	return b.String()
+}
+
+// wishSVGHandler handles requests for SVG responses for wishes.
+func wishSVGHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	validName, err := validateName(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	style, opts, err := artFromRequest(r, validName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	theme, err := svgThemeFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	formatUsage.inc("svg")
+	setSVGHeaders(w)
+	fmt.Fprint(w, renderSVG(newGreeting(validName, style, opts), theme))
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// pages holds one parsed template set per page, each combining the shared
+// layout and partials with the page's own blocks.
+var pages = mustLoadTemplates("")
+
+// pageMeta holds the per-request values used by the shared layout and
+// partials. Every page's data embeds it.
+type pageMeta struct {
+	// Nonce authorizes the page's inline <script> and <style> elements
+	// under the Content-Security-Policy.
+	Nonce string
+}
+
+func newPageMeta(r *http.Request) pageMeta {
+	return pageMeta{Nonce: cspNonce(r.Context())}
+}
+
+// wishPage is the data rendered by the wish page template.
+type wishPage struct {
+	pageMeta
+	Name        string
+	Slug        string
+	Art         string
+	ShareURL    string
+	TextURL     string
+	ImageURL    string
+	ImagePath   string
+	DownloadURL string
+}
+
+// landingPage is the data rendered by the home and not-found templates.
+type landingPage struct {
+	pageMeta
+	ImageURL string
+}
+
+// overlayFS serves files from upper when present and from lower otherwise,
+// so a templates directory only needs to contain the files it overrides.
+type overlayFS struct {
+	upper fs.FS
+	lower fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if f, err := o.upper.Open(name); err == nil {
+		return f, nil
+	}
+	return o.lower.Open(name)
+}
+
+// loadTemplates parses the embedded templates, letting files in dir (when
+// non-empty) override embedded files of the same relative path.
+func loadTemplates(dir string) (map[string]*template.Template, error) {
+	base, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	src := base
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("templates directory: %w", err)
+		}
+		src = overlayFS{upper: os.DirFS(dir), lower: base}
+	}
+
+	partials, err := fs.Glob(base, "partials/*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	shared := template.New("base").Funcs(template.FuncMap{"asset": assetPath})
+	for _, name := range append([]string{"layout.html"}, partials...) {
+		if err := parseTemplateFile(shared, src, name); err != nil {
+			return nil, err
+		}
+	}
+
+	pageFiles, err := fs.Glob(base, "pages/*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[string]*template.Template, len(pageFiles))
+	for _, name := range pageFiles {
+		t, err := shared.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if err := parseTemplateFile(t, src, name); err != nil {
+			return nil, err
+		}
+		loaded[strings.TrimSuffix(path.Base(name), ".html")] = t
+	}
+	return loaded, nil
+}
+
+func parseTemplateFile(t *template.Template, fsys fs.FS, name string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	if _, err := t.New(name).Parse(string(data)); err != nil {
+		return fmt.Errorf("parse %s: %w", name, err)
+	}
+	return nil
+}
+
+func mustLoadTemplates(dir string) map[string]*template.Template {
+	t, err := loadTemplates(dir)
+	if err != nil {
+		log.Fatalf("Failed to load templates: %v", err)
+	}
+	return t
+}
+
+// renderPage executes the named page into a buffer and writes it with the
+// given status, so a template error never produces a half-written page.
+func renderPage(w http.ResponseWriter, status int, page string, data any) {
+	t, ok := pages[page]
+	if !ok {
+		log.Printf("Template %q not found", page)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "layout", data); err != nil {
+		log.Printf("Template %q failed: %v", page, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	setHTMLHeaders(w)
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
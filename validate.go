@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxNameLength is the longest accepted name in user-perceived characters
+// (grapheme clusters), so "அருண்" counts 3 and a family emoji counts 1.
+const maxNameLength = 36
+
+// maxNameBytes bounds the input before any Unicode processing. No name of
+// maxNameLength clusters realistically needs more.
+const maxNameBytes = maxNameLength * 16
+
+var (
+	errNameLength      = errors.New("name length must be between 1 and 36 characters")
+	errNameInvalid     = errors.New("name contains invalid characters")
+	errNameEncoding    = errors.New("name is not valid UTF-8")
+	errNameControl     = errors.New("name contains control characters")
+	errNameBidi        = errors.New("name contains bidirectional override characters")
+	errNameInvisible   = errors.New("name contains zero-width or invisible characters")
+	errNameMixedScript = errors.New("name mixes scripts that can be confused with each other")
+)
+
+// nameErrorCode maps a validateName error to a machine-readable code.
+func nameErrorCode(err error) string {
+	switch {
+	case errors.Is(err, errNameLength):
+		return "name_length"
+	case errors.Is(err, errNameInvalid):
+		return "name_invalid_characters"
+	case errors.Is(err, errNameEncoding):
+		return "name_invalid_encoding"
+	case errors.Is(err, errNameControl):
+		return "name_control_characters"
+	case errors.Is(err, errNameBidi):
+		return "name_bidi_override"
+	case errors.Is(err, errNameInvisible):
+		return "name_invisible_characters"
+	case errors.Is(err, errNameMixedScript):
+		return "name_mixed_script"
+	}
+	return "name_invalid"
+}
+
+// nameCharacters are the character classes a name may consist of. Zero-width
+// joiners are listed here but only accepted where isJoinerAllowed says so.
+var nameCharacters = regexp.MustCompile(`^[\p{L}\p{N}\p{P}\p{Zs}\p{M}\p{Sm}\p{So}\p{Sk}\x{200C}\x{200D}]+$`)
+
+// isBidiControl reports whether r overrides or isolates text direction,
+// which can make a name display differently from how it is stored.
+func isBidiControl(r rune) bool {
+	switch {
+	case r == '\u061C', r == '\u200E', r == '\u200F':
+		return true
+	case r >= '\u202A' && r <= '\u202E':
+		return true
+	case r >= '\u2066' && r <= '\u2069':
+		return true
+	}
+	return false
+}
+
+// isJoinerAllowed reports whether the zero-width (non-)joiner at runes[i]
+// is doing legitimate shaping work: joining emoji into one sequence, or
+// controlling ligatures between letters of a non-Latin script.
+func isJoinerAllowed(runes []rune, i int) bool {
+	if i == 0 || i == len(runes)-1 {
+		return false
+	}
+	prev, next := runes[i-1], runes[i+1]
+	if runes[i] == '\u200D' && isEmojiPart(prev) && isEmojiPart(next) {
+		return true
+	}
+	return isShapedLetter(prev) && isShapedLetter(next)
+}
+
+func isEmojiPart(r rune) bool {
+	return unicode.Is(unicode.So, r) || r == '\uFE0F' || unicode.Is(unicode.Sk, r)
+}
+
+func isShapedLetter(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsMark(r)) && r >= 0x0370 && !unicode.Is(unicode.Latin, r)
+}
+
+// scriptOf returns the script of a letter, or "" for characters shared
+// between scripts (digits, punctuation, combining marks).
+func scriptOf(r rune) string {
+	for name, table := range unicode.Scripts {
+		if name == "Common" || name == "Inherited" {
+			continue
+		}
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
+
+// confusableScripts look enough like Latin, and each other, that mixing
+// them is the classic way to spoof a name ("Аlice" with a Cyrillic А).
+var confusableScripts = map[string]bool{"Latin": true, "Cyrillic": true, "Greek": true, "Cherokee": true}
+
+// eastAsianScripts may be mixed with Latin and Han as they are in ordinary
+// Chinese, Japanese and Korean writing.
+var eastAsianScripts = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"},
+	{"Latin", "Han", "Bopomofo"},
+	{"Latin", "Han", "Hangul"},
+}
+
+// mixedScript reports whether name combines scripts in a way that allows
+// spoofing, following the "moderately restrictive" profile of Unicode
+// TS #39: a single script, the usual CJK combinations, or Latin plus one
+// other script that is not confusable with it.
+func mixedScript(name string) bool {
+	scripts := make(map[string]bool)
+	for _, r := range name {
+		if s := scriptOf(r); s != "" {
+			scripts[s] = true
+		}
+	}
+	if len(scripts) <= 1 {
+		return false
+	}
+	for _, allowed := range eastAsianScripts {
+		if subsetOf(scripts, allowed) {
+			return false
+		}
+	}
+	if len(scripts) == 2 && scripts["Latin"] {
+		for s := range scripts {
+			if s != "Latin" && confusableScripts[s] {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func subsetOf(set map[string]bool, list []string) bool {
+	n := 0
+	for _, s := range list {
+		if set[s] {
+			n++
+		}
+	}
+	return n == len(set)
+}
+
+// validateName checks a name from a request and returns it in Unicode
+// normalization form C, so visually identical input always produces the
+// same art, slug and quote.
+func validateName(name string) (string, error) {
+	valid, err := checkName(name)
+	if err != nil {
+		validationFailures.inc(strings.TrimPrefix(nameErrorCode(err), "name_"))
+		return "", err
+	}
+	return valid, nil
+}
+
+func checkName(name string) (string, error) {
+	if len(name) == 0 || len(name) > maxNameBytes {
+		return "", errNameLength
+	}
+	if !utf8.ValidString(name) {
+		return "", errNameEncoding
+	}
+	name = norm.NFC.String(name)
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case unicode.IsControl(r):
+			return "", errNameControl
+		case isBidiControl(r):
+			return "", errNameBidi
+		case r == '\u200C' || r == '\u200D':
+			if !isJoinerAllowed(runes, i) {
+				return "", errNameInvisible
+			}
+		case unicode.Is(unicode.Cf, r), unicode.Is(unicode.Other_Default_Ignorable_Code_Point, r):
+			return "", errNameInvisible
+		}
+	}
+
+	if !nameCharacters.MatchString(name) {
+		return "", errNameInvalid
+	}
+	if n := uniseg.GraphemeClusterCount(name); n > maxNameLength {
+		return "", errNameLength
+	}
+	if mixedScript(name) {
+		return "", errNameMixedScript
+	}
+	return name, nil
+}
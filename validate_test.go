@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{"Sam", "Sam", nil},
+		{"Mary Jane", "Mary Jane", nil},
+		{"O'Brien", "O'Brien", nil},
+		{"R2-D2", "R2-D2", nil},
+		{"José", "José", nil},
+		{"Jose\u0301", "José", nil},
+		{"அருண்", "அருண்", nil},
+		{"Arun அருண்", "Arun அருண்", nil},
+		{"Алиса", "Алиса", nil},
+		{"山田 さくら", "山田 さくら", nil},
+		{"Sam 👨\u200D👩\u200D👧", "Sam 👨\u200D👩\u200D👧", nil},
+		{"می\u200Cخواهم", "می\u200Cخواهم", nil},
+		{strings.Repeat("a", 36), strings.Repeat("a", 36), nil},
+		{strings.Repeat("அ", 36), strings.Repeat("அ", 36), nil},
+		{strings.Repeat("👍🏽", 36), strings.Repeat("👍🏽", 36), nil},
+		{"", "", errNameLength},
+		{strings.Repeat("a", 37), "", errNameLength},
+		{strings.Repeat("😀", 37), "", errNameLength},
+		{strings.Repeat("a", maxNameBytes+1), "", errNameLength},
+		{"Sam\xff", "", errNameEncoding},
+		{"Sam\x00", "", errNameControl},
+		{"Sam\nJane", "", errNameControl},
+		{"Sam\u202Eevil", "", errNameBidi},
+		{"Sam\u2066", "", errNameBidi},
+		{"Sa\u200Bm", "", errNameInvisible},
+		{"Sam\uFEFF", "", errNameInvisible},
+		{"Sa\u200Dm", "", errNameInvisible},
+		{"\u200DSam", "", errNameInvisible},
+		{"Sam\u3164", "", errNameInvisible},
+		{"Sam$", "", errNameInvalid},
+		{"Аlice", "", errNameMixedScript},
+		{"Pαul", "", errNameMixedScript},
+		{"Алиса அருண்", "", errNameMixedScript},
+	}
+	for _, tt := range tests {
+		got, err := validateName(tt.name)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("validateName(%q) error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("validateName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNameErrorCodes(t *testing.T) {
+	errs := []error{errNameLength, errNameInvalid, errNameEncoding, errNameControl, errNameBidi, errNameInvisible, errNameMixedScript}
+	seen := make(map[string]bool)
+	for _, err := range errs {
+		code := nameErrorCode(err)
+		if code == "name_invalid" || seen[code] {
+			t.Errorf("nameErrorCode(%v) = %q, want a distinct code", err, code)
+		}
+		seen[code] = true
+	}
+}
+
+func FuzzValidateName(f *testing.F) {
+	for _, seed := range []string{"Sam", "", "José", "Sam\x00", strings.Repeat("a", 37), "😀", "a\u200Db", "Аlice", "அருண்"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		got, err := validateName(name)
+		if err != nil {
+			if nameErrorCode(err) == "name_invalid" {
+				t.Errorf("validateName(%q) returned an error without a code: %v", name, err)
+			}
+			return
+		}
+		if got == "" {
+			t.Errorf("validateName(%q) accepted an empty name", name)
+		}
+		if !norm.NFC.IsNormalString(got) {
+			t.Errorf("validateName(%q) = %q is not NFC", name, got)
+		}
+		if n := uniseg.GraphemeClusterCount(got); n > maxNameLength {
+			t.Errorf("validateName(%q) accepted %d characters", name, n)
+		}
+		for _, r := range got {
+			if unicode.IsControl(r) || isBidiControl(r) {
+				t.Errorf("validateName(%q) accepted %U", name, r)
+			}
+		}
+		if again, err := validateName(got); err != nil || again != got {
+			t.Errorf("validateName is not idempotent: %q -> %q -> %q, %v", name, got, again, err)
+		}
+	})
+}
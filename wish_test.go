@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// setConfig replaces cfg for the duration of the test.
+func setConfig(t *testing.T, modify func(c *config)) {
+	t.Helper()
+	saved := cfg
+	c := defaultConfig()
+	modify(&c)
+	cfg = c
+	t.Cleanup(func() { cfg = saved })
+}
+
+// checkGolden compares got with testdata/<name>, rewriting the file when
+// the tests run with -update.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s (run go test -update to accept)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Sam", "Sam"},
+		{"  Sam  ", "Sam"},
+		{"mary-jane", "mary jane"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := cleanName(tt.name); got != tt.want {
+			t.Errorf("cleanName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestImageURL(t *testing.T) {
+	tests := []struct {
+		backend string
+		want    string
+	}{
+		{imageBackendLocal, "https://wish.example/wish/image.png?name=sam"},
+		{"https://img.example/card", "https://img.example/card?name=sam"},
+		{"https://img.example/card?size=2", "https://img.example/card?size=2&name=sam"},
+	}
+	for _, tt := range tests {
+		setConfig(t, func(c *config) { c.ImageBackend = tt.backend })
+		if got := imageURL("https://wish.example", "sam"); got != tt.want {
+			t.Errorf("imageURL with backend %q = %q, want %q", tt.backend, got, tt.want)
+		}
+	}
+}
+
+func TestWishTextGolden(t *testing.T) {
+	tests := []struct {
+		file   string
+		target string
+	}{
+		{"text_friend.golden", "/wish/text/sam"},
+		{"text_heart.golden", "/wish/text/mary-jane?style=heart"},
+		{"text_card.golden", "/wish/text/sam?style=card&quote=stars"},
+		{"text_cowsay.golden", "/wish/text/sam?style=cowsay&font=small"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			rec := doRequest(t, http.MethodGet, tt.target, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body)
+			}
+			checkGolden(t, tt.file, rec.Body.String())
+		})
+	}
+}
+
+func TestWishHTMLGolden(t *testing.T) {
+	saved := newCSPNonce
+	newCSPNonce = func() string { return "golden-nonce" }
+	t.Cleanup(func() { newCSPNonce = saved })
+
+	rec := doRequest(t, http.MethodGet, "/wish/web/mary-jane", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+	checkGolden(t, "web_wish.golden", rec.Body.String())
+}